@@ -0,0 +1,457 @@
+package mysqldump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+
+	driver "github.com/go-sql-driver/mysql"
+
+	gmysql "github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+type binlogOption struct {
+	// 起始 binlog 文件名和位置, 与 gtidSet 互斥, gtidSet 优先级高
+	startFile string
+	startPos  uint32
+
+	// 起始 GTID 集合, 优先级高于 startFile/startPos
+	gtidSet string
+
+	// 停止点, 可以是 "file:pos" 形式, 也可以是 GTID, 为空表示不停止
+	stopAt string
+
+	// 只导出指定表的变更, 为空表示导出全部表
+	filterTables []string
+
+	// 是否持续跟随 binlog, 默认 false, 即读到当前最新位置后退出
+	follow bool
+
+	// writer 默认为 os.Stdout
+	writer io.Writer
+}
+
+type BinlogOption func(*binlogOption)
+
+// WithBinlogStart 指定起始 binlog 文件名和位置
+func WithBinlogStart(file string, pos uint32) BinlogOption {
+	return func(o *binlogOption) {
+		o.startFile = file
+		o.startPos = pos
+	}
+}
+
+// WithBinlogGTIDSet 指定起始 GTID 集合, 优先级高于 WithBinlogStart
+func WithBinlogGTIDSet(set string) BinlogOption {
+	return func(o *binlogOption) {
+		o.gtidSet = set
+	}
+}
+
+// WithBinlogStopAt 指定停止点, 可以是 "file:pos" 形式, 也可以是 GTID
+func WithBinlogStopAt(stopAt string) BinlogOption {
+	return func(o *binlogOption) {
+		o.stopAt = stopAt
+	}
+}
+
+// WithBinlogFilterTables 只导出指定表的变更, 默认导出全部表
+func WithBinlogFilterTables(tables ...string) BinlogOption {
+	return func(o *binlogOption) {
+		o.filterTables = tables
+	}
+}
+
+// WithBinlogFollow 持续跟随 binlog, 默认读到当前最新位置后退出
+func WithBinlogFollow() BinlogOption {
+	return func(o *binlogOption) {
+		o.follow = true
+	}
+}
+
+// WithBinlogWriter 导出到指定 writer, 默认为 os.Stdout
+func WithBinlogWriter(writer io.Writer) BinlogOption {
+	return func(o *binlogOption) {
+		o.writer = writer
+	}
+}
+
+// tableSchema 缓存一张表的全部列名(按 ORDINAL_POSITION 排序, 下标与 binlog
+// ROWS_EVENT 里的行数据下标一一对应)以及主键列在其中的下标, 用于生成真实
+// 列名的 UPDATE/DELETE 语句, 而不是占位的 col0/col1/...
+type tableSchema struct {
+	cols []string
+	pk   []int
+}
+
+// schemaCache 按表名缓存 tableSchema, 避免每个 ROWS_EVENT 都查一次
+// INFORMATION_SCHEMA
+type schemaCache map[string]tableSchema
+
+// DumpBinlog 以复制协议连接 MySQL (伪装为 slave), 从指定的 (file, pos) 或 GTID 集合
+// 开始持续解析 ROW 格式的 binlog 事件, 按事务 (XID_EVENT 边界) 生成等价的
+// INSERT / UPDATE ... WHERE pk=... / DELETE FROM ... WHERE pk=... 语句, 每个事务前
+// 附带一行 "-- GTID: ..." 注释。生成的脚本可以直接交给 Source 回放, 用于在全量
+// 备份的基础上做时间点恢复(PITR)。
+func DumpBinlog(dsn string, opts ...BinlogOption) error {
+	var o binlogOption
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if o.writer == nil {
+		o.writer = os.Stdout
+	}
+	buf := bufio.NewWriter(o.writer)
+	defer buf.Flush()
+
+	cfg, err := driver.ParseDSN(dsn)
+	if err != nil {
+		log.Printf("[error] %v \n", err)
+		return err
+	}
+	host, port, err := splitHostPort(cfg.Addr)
+	if err != nil {
+		log.Printf("[error] %v \n", err)
+		return err
+	}
+
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		log.Printf("[error] %v \n", err)
+		return err
+	}
+	defer db.Close()
+
+	filter := make(map[string]bool, len(o.filterTables))
+	for _, t := range o.filterTables {
+		filter[t] = true
+	}
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID: 100,
+		Flavor:   "mysql",
+		Host:     host,
+		Port:     port,
+		User:     cfg.User,
+		Password: cfg.Passwd,
+	})
+	defer syncer.Close()
+
+	var streamer *replication.BinlogStreamer
+	if o.gtidSet != "" {
+		set, err := gmysql.ParseMysqlGTIDSet(o.gtidSet)
+		if err != nil {
+			log.Printf("[error] %v \n", err)
+			return err
+		}
+		streamer, err = syncer.StartSyncGTID(set)
+	} else {
+		streamer, err = syncer.StartSync(gmysql.Position{Name: o.startFile, Pos: o.startPos})
+	}
+	if err != nil {
+		log.Printf("[error] %v \n", err)
+		return err
+	}
+
+	// 非 follow 模式下, 终止条件是"读到启动时刻的最新位置", 必须在开始消费流之前
+	// 就把这个目标位置固定下来, 不能指望服务器一定会发送心跳事件(心跳只有配置
+	// 了心跳周期的 syncer 才会收到, 否则会一直阻塞在 GetEvent 上)
+	var endFile string
+	var endPos uint32
+	if !o.follow {
+		endFile, endPos, err = fetchMasterStatus(context.Background(), db)
+		if err != nil {
+			log.Printf("[error] %v \n", err)
+			return err
+		}
+	}
+
+	schemas := make(schemaCache)
+	curFile := o.startFile
+	var curPos uint32 = o.startPos
+	var curGTID string
+	var txStatements []string
+
+	for {
+		ev, err := streamer.GetEvent(context.Background())
+		if err != nil {
+			log.Printf("[error] %v \n", err)
+			return err
+		}
+		curPos = ev.Header.LogPos
+
+		switch e := ev.Event.(type) {
+		case *replication.RotateEvent:
+			curFile = string(e.NextLogName)
+			curPos = uint32(e.Position)
+		case *replication.GTIDEvent:
+			curGTID = formatGTID(e)
+		case *replication.RowsEvent:
+			table := e.Table
+			if table == nil {
+				continue
+			}
+			tableName := string(table.Table)
+			if len(filter) > 0 && !filter[tableName] {
+				continue
+			}
+			colNames, pkIdx, err := loadPKColumns(db, schemas, string(table.Schema), tableName)
+			if err != nil {
+				log.Printf("[error] %v \n", err)
+				return err
+			}
+			stmts, err := rowsEventToSQL(ev.Header.EventType, tableName, e.Rows, colNames, pkIdx)
+			if err != nil {
+				log.Printf("[error] %v \n", err)
+				return err
+			}
+			txStatements = append(txStatements, stmts...)
+		case *replication.XIDEvent:
+			if len(txStatements) > 0 {
+				buf.WriteString(fmt.Sprintf("-- GTID: %s\n", curGTID))
+				for _, s := range txStatements {
+					buf.WriteString(s)
+					buf.WriteString("\n")
+				}
+				buf.WriteString("\n")
+				buf.Flush()
+				txStatements = nil
+			}
+		}
+
+		if o.stopAt != "" && reachedStop(o.stopAt, curFile, curPos, curGTID) {
+			break
+		}
+		if !o.follow && curFile == endFile && curPos >= endPos {
+			break
+		}
+	}
+
+	return nil
+}
+
+// fetchMasterStatus 查询 SHOW MASTER STATUS 得到当前最新的 binlog 位置, 在
+// 非 follow 模式下作为"读到最新位置后退出"的终止条件, 避免依赖服务器不一定
+// 会发送的心跳事件
+func fetchMasterStatus(ctx context.Context, db dbConn) (string, uint32, error) {
+	rows, err := db.QueryContext(ctx, "SHOW MASTER STATUS")
+	if err != nil {
+		return "", 0, err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", 0, err
+	}
+	if !rows.Next() {
+		return "", 0, fmt.Errorf("SHOW MASTER STATUS returned no rows, binary logging may be disabled")
+	}
+
+	var file string
+	var pos uint32
+	dest := make([]interface{}, len(cols))
+	for i := range dest {
+		switch i {
+		case 0:
+			dest[i] = &file
+		case 1:
+			dest[i] = &pos
+		default:
+			var ignore sql.NullString
+			dest[i] = &ignore
+		}
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return "", 0, err
+	}
+	return file, pos, nil
+}
+
+// reachedStop 判断当前位置(file:pos 或 gtid)是否已到达调用方指定的停止点
+func reachedStop(stopAt, curFile string, curPos uint32, curGTID string) bool {
+	if stopAt == curGTID {
+		return true
+	}
+	if idx := strings.LastIndex(stopAt, ":"); idx != -1 {
+		file := stopAt[:idx]
+		if file != curFile {
+			return false
+		}
+		stopPos, err := strconv.ParseUint(stopAt[idx+1:], 10, 32)
+		if err != nil {
+			return false
+		}
+		return uint64(curPos) >= stopPos
+	}
+	return false
+}
+
+// formatGTID 将 GTID_EVENT 中的 SID/GNO 拼接为标准的 "uuid:gno" 形式
+func formatGTID(e *replication.GTIDEvent) string {
+	u := e.SID
+	return fmt.Sprintf("%x-%x-%x-%x-%x:%d", u[0:4], u[4:6], u[6:8], u[8:10], u[10:16], e.GNO)
+}
+
+// loadPKColumns 查询并缓存表的全部列名及主键列下标, 列名用于把 UPDATE/DELETE
+// 还原成引用真实列名的 SQL, 下标用于拼接 WHERE 条件时取主键列
+func loadPKColumns(db *sql.DB, cache schemaCache, schema, table string) ([]string, []int, error) {
+	if ts, ok := cache[table]; ok {
+		return ts.cols, ts.pk, nil
+	}
+	rows, err := db.Query(
+		"SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' "+
+			"ORDER BY ORDINAL_POSITION", schema, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, nil, err
+		}
+		names = append(names, name)
+	}
+
+	colRows, err := db.Query(
+		"SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS "+
+			"WHERE TABLE_SCHEMA = ? AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", schema, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer colRows.Close()
+
+	var allCols []string
+	for colRows.Next() {
+		var name string
+		if err := colRows.Scan(&name); err != nil {
+			return nil, nil, err
+		}
+		allCols = append(allCols, name)
+	}
+
+	var idx []int
+	for _, pk := range names {
+		for i, c := range allCols {
+			if c == pk {
+				idx = append(idx, i)
+				break
+			}
+		}
+	}
+	cache[table] = tableSchema{cols: allCols, pk: idx}
+	return allCols, idx, nil
+}
+
+// rowsEventToSQL 将一个 WRITE/UPDATE/DELETE ROWS_EVENT 转换成等价的 SQL 语句。
+// cols 是该表按 ORDINAL_POSITION 排序的真实列名, 下标与 rows 里每行的下标
+// 一一对应, 用于生成引用真实列名而不是 col0/col1 占位符的 SET/WHERE 子句
+func rowsEventToSQL(eventType replication.EventType, table string, rows [][]interface{}, cols []string, pk []int) ([]string, error) {
+	var stmts []string
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		for _, row := range rows {
+			values := make([]string, len(row))
+			names := make([]string, len(row))
+			for i, v := range row {
+				values[i] = formatBinlogValue(v)
+				names[i] = quoteIdent(colNameAt(cols, i))
+			}
+			stmts = append(stmts, fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s);", quoteIdent(table), strings.Join(names, ","), strings.Join(values, ",")))
+		}
+	case replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		for _, row := range rows {
+			where, err := buildWhere(row, cols, pk)
+			if err != nil {
+				return nil, err
+			}
+			stmts = append(stmts, fmt.Sprintf("DELETE FROM %s WHERE %s;", quoteIdent(table), where))
+		}
+	case replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		// UPDATE 事件的 rows 按 (before, after) 成对出现
+		for i := 0; i+1 < len(rows); i += 2 {
+			before, after := rows[i], rows[i+1]
+			where, err := buildWhere(before, cols, pk)
+			if err != nil {
+				return nil, err
+			}
+			var sets []string
+			for j, v := range after {
+				sets = append(sets, fmt.Sprintf("%s=%s", quoteIdent(colNameAt(cols, j)), formatBinlogValue(v)))
+			}
+			stmts = append(stmts, fmt.Sprintf("UPDATE %s SET %s WHERE %s;", quoteIdent(table), strings.Join(sets, ","), where))
+		}
+	}
+	return stmts, nil
+}
+
+// colNameAt 返回下标 i 对应的真实列名, 查不到时(行数据和读到的表结构对不上)
+// 退化为 col<i> 占位符, 不中断导出
+func colNameAt(cols []string, i int) string {
+	if i >= 0 && i < len(cols) {
+		return cols[i]
+	}
+	return fmt.Sprintf("col%d", i)
+}
+
+// buildWhere 依据主键列拼接 WHERE 条件, 没有主键时退化为按全部列匹配
+func buildWhere(row []interface{}, cols []string, pk []int) (string, error) {
+	if len(row) == 0 {
+		return "", fmt.Errorf("empty row")
+	}
+	idx := pk
+	if len(idx) == 0 {
+		idx = make([]int, len(row))
+		for i := range row {
+			idx[i] = i
+		}
+	}
+	var conds []string
+	for _, i := range idx {
+		conds = append(conds, fmt.Sprintf("%s=%s", quoteIdent(colNameAt(cols, i)), formatBinlogValue(row[i])))
+	}
+	return strings.Join(conds, " AND "), nil
+}
+
+// formatBinlogValue 将 binlog 解析出的列值格式化为可以直接拼进 SQL 的字面量,
+// 转义规则与 mysqlDialect.FormatValue 共用同一个 escapeString, 正确处理嵌入的
+// 反斜杠、NUL 字节等 strings.NewReplacer 处理不了的情况
+func formatBinlogValue(v interface{}) string {
+	if v == nil {
+		return "NULL"
+	}
+	switch t := v.(type) {
+	case []byte:
+		return fmt.Sprintf("'%s'", escapeString(string(t), false))
+	case string:
+		return fmt.Sprintf("'%s'", escapeString(t, false))
+	default:
+		return fmt.Sprintf("%v", t)
+	}
+}
+
+// splitHostPort 从 go-sql-driver 的 Addr 字段 (host:port) 中拆出复制客户端需要的
+// host 和 port
+func splitHostPort(addr string) (string, uint16, error) {
+	idx := strings.LastIndex(addr, ":")
+	if idx == -1 {
+		return "", 0, fmt.Errorf("invalid addr: %s", addr)
+	}
+	host := addr[:idx]
+	var port uint16
+	if _, err := fmt.Sscanf(addr[idx+1:], "%d", &port); err != nil {
+		return "", 0, fmt.Errorf("invalid addr: %s", addr)
+	}
+	return host, port, nil
+}
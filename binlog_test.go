@@ -0,0 +1,121 @@
+package mysqldump
+
+import (
+	"testing"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+func Test_reachedStop(t *testing.T) {
+	type args struct {
+		stopAt  string
+		curFile string
+		curPos  uint32
+		curGTID string
+	}
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{
+			name: "pos not yet reached",
+			args: args{stopAt: "bin.000001:100", curFile: "bin.000001", curPos: 99},
+			want: false,
+		},
+		{
+			name: "pos exactly reached",
+			args: args{stopAt: "bin.000001:100", curFile: "bin.000001", curPos: 100},
+			want: true,
+		},
+		{
+			name: "pos reached far past target",
+			args: args{stopAt: "bin.000001:100", curFile: "bin.000001", curPos: 1000},
+			want: true,
+		},
+		{
+			name: "different file never reaches",
+			args: args{stopAt: "bin.000001:100", curFile: "bin.000002", curPos: 1000},
+			want: false,
+		},
+		{
+			name: "gtid match",
+			args: args{stopAt: "3E11FA47-71CA-11E1-9E33-C80AA9429562:23", curGTID: "3E11FA47-71CA-11E1-9E33-C80AA9429562:23"},
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := reachedStop(tt.args.stopAt, tt.args.curFile, tt.args.curPos, tt.args.curGTID); got != tt.want {
+				t.Errorf("reachedStop() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_rowsEventToSQL(t *testing.T) {
+	cols := []string{"id", "name"}
+
+	t.Run("insert emits explicit column list", func(t *testing.T) {
+		rows := [][]interface{}{{int64(1), "a"}}
+		stmts, err := rowsEventToSQL(replication.WRITE_ROWS_EVENTv2, "t", rows, cols, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := "INSERT INTO `t` (`id`,`name`) VALUES (1,'a');"
+		if len(stmts) != 1 || stmts[0] != want {
+			t.Errorf("rowsEventToSQL() = %v, want [%s]", stmts, want)
+		}
+	})
+
+	t.Run("update references real column names", func(t *testing.T) {
+		rows := [][]interface{}{
+			{int64(1), "a"},
+			{int64(1), "b"},
+		}
+		stmts, err := rowsEventToSQL(replication.UPDATE_ROWS_EVENTv2, "t", rows, cols, []int{0})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := "UPDATE `t` SET `id`=1,`name`='b' WHERE `id`=1;"
+		if len(stmts) != 1 || stmts[0] != want {
+			t.Errorf("rowsEventToSQL() = %v, want [%s]", stmts, want)
+		}
+	})
+
+	t.Run("delete references real column names", func(t *testing.T) {
+		rows := [][]interface{}{{int64(1), "a"}}
+		stmts, err := rowsEventToSQL(replication.DELETE_ROWS_EVENTv2, "t", rows, cols, []int{0})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		want := "DELETE FROM `t` WHERE `id`=1;"
+		if len(stmts) != 1 || stmts[0] != want {
+			t.Errorf("rowsEventToSQL() = %v, want [%s]", stmts, want)
+		}
+	})
+}
+
+func Test_buildWhere(t *testing.T) {
+	cols := []string{"id", "name"}
+
+	t.Run("with primary key", func(t *testing.T) {
+		where, err := buildWhere([]interface{}{int64(1), "a"}, cols, []int{0})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "`id`=1"; where != want {
+			t.Errorf("buildWhere() = %q, want %q", where, want)
+		}
+	})
+
+	t.Run("without primary key falls back to all columns", func(t *testing.T) {
+		where, err := buildWhere([]interface{}{int64(1), "a"}, cols, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if want := "`id`=1 AND `name`='a'"; where != want {
+			t.Errorf("buildWhere() = %q, want %q", where, want)
+		}
+	})
+}
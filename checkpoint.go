@@ -0,0 +1,81 @@
+package mysqldump
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"time"
+)
+
+type onErrorKind int
+
+const (
+	onErrorAbort onErrorKind = iota
+	onErrorSkip
+	onErrorRetry
+)
+
+// OnErrorPolicy 描述 Source 执行单条语句失败时的处理方式, 通过 Abort /
+// SkipAndLog / RetryN 构造, 配合 WithOnError 使用
+type OnErrorPolicy struct {
+	kind    onErrorKind
+	retries int
+	backoff time.Duration
+}
+
+// Abort 是默认策略: 遇到错误立即终止恢复, 与不设置 WithOnError 行为一致
+func Abort() OnErrorPolicy {
+	return OnErrorPolicy{kind: onErrorAbort}
+}
+
+// SkipAndLog 遇到错误时记录日志并跳过该语句, 继续执行后续语句
+func SkipAndLog() OnErrorPolicy {
+	return OnErrorPolicy{kind: onErrorSkip}
+}
+
+// RetryN 遇到错误后按 backoff 间隔重试最多 n 次, 仍然失败则按 Abort 处理
+func RetryN(n int, backoff time.Duration) OnErrorPolicy {
+	return OnErrorPolicy{kind: onErrorRetry, retries: n, backoff: backoff}
+}
+
+// checkpointState 是持久化到检查点文件里的内容: reader 的字节偏移量, 加上
+// 最近一条成功执行语句的哈希, 方便排查续传位置是否符合预期
+type checkpointState struct {
+	Offset       int64  `json:"offset"`
+	LastStmtHash string `json:"last_stmt_hash"`
+}
+
+func loadCheckpoint(path string) (*checkpointState, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var cp checkpointState
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, err
+	}
+	return &cp, nil
+}
+
+// saveCheckpoint 先写临时文件再 rename 到目标路径, 保证检查点文件要么是上一次
+// 完整的内容, 要么是这一次完整的内容, 不会出现写到一半被中断的损坏文件
+func saveCheckpoint(path string, cp checkpointState) error {
+	data, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func hashStatement(ssql string) string {
+	sum := sha256.Sum256([]byte(ssql))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,40 @@
+package mysqldump
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func Test_saveCheckpoint_loadCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+
+	if cp, err := loadCheckpoint(path); err != nil || cp != nil {
+		t.Fatalf("loadCheckpoint() on missing file = (%v, %v), want (nil, nil)", cp, err)
+	}
+
+	want := checkpointState{Offset: 12345, LastStmtHash: hashStatement("INSERT INTO t VALUES (1);")}
+	if err := saveCheckpoint(path, want); err != nil {
+		t.Fatalf("saveCheckpoint() error = %v", err)
+	}
+
+	got, err := loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() error = %v", err)
+	}
+	if got == nil || *got != want {
+		t.Errorf("loadCheckpoint() = %v, want %v", got, want)
+	}
+
+	// 续传时每次提交都会覆盖之前的检查点, 偏移量只会前进
+	next := checkpointState{Offset: 54321, LastStmtHash: hashStatement("INSERT INTO t VALUES (2);")}
+	if err := saveCheckpoint(path, next); err != nil {
+		t.Fatalf("saveCheckpoint() overwrite error = %v", err)
+	}
+	got, err = loadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("loadCheckpoint() error = %v", err)
+	}
+	if got == nil || *got != next {
+		t.Errorf("loadCheckpoint() after overwrite = %v, want %v", got, next)
+	}
+}
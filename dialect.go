@@ -0,0 +1,61 @@
+package mysqldump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"strings"
+)
+
+// dbConn 是 Dialect 查询方法实际依赖的最小接口, *sql.DB 和 *sql.Conn 都满足它。
+// 并行/一致性快照导出时需要把 START TRANSACTION 和随后所有的 SELECT 钉在
+// 同一个物理连接上, 而 *sql.DB 的方法每次都会从连接池里任取一个连接, 所以这里
+// 改用该接口, 调用方可以传入一个 db.Conn(ctx) 取到的 *sql.Conn 来满足这个要求;
+// 不需要事务语义时, 直接传 *sql.DB 本身即可
+type dbConn interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// Dialect 封装了一种数据库方言下的 SQL 生成/读取方式, Dump 的主流程只依赖
+// 这个接口, 不再关心具体是 MySQL/PostgreSQL/SQL Server。新增一种方言只需
+// 实现该接口, 不需要改动 Dump 本身。
+type Dialect interface {
+	// DriverName 是 database/sql 注册该方言时使用的 driver 名称
+	DriverName() string
+	// QuoteIdent 给标识符(库名/表名/列名)加上方言自己的引号
+	QuoteIdent(name string) string
+	// UseStmt 返回切换到指定库的语句, 不支持 USE 语义的方言可以返回空字符串
+	UseStmt(dbName string) string
+	// ListTables 列出当前库下需要导出的表
+	ListTables(db dbConn) ([]string, error)
+	// GetTableType 返回 "TABLE" 或 "VIEW"
+	GetTableType(db dbConn, table string) (string, error)
+	// DropTableStmt / DropViewStmt 返回删除表/视图的语句
+	DropTableStmt(table string) string
+	DropViewStmt(table string) string
+	// WriteTableStruct / WriteViewStruct 导出表/视图结构
+	WriteTableStruct(db dbConn, table string, buf *bufio.Writer) error
+	WriteViewStruct(db dbConn, table string, buf *bufio.Writer) error
+	// WriteTableData 导出表数据。chunkSize > 0 时按该行数分批查询, 避免大表一次性
+	// 把结果集全部加载进内存; chunkSize <= 0 时退化为一次性 SELECT *
+	WriteTableData(db dbConn, table string, buf *bufio.Writer, perDataNumber int, chunkSize int) error
+	// WriteTableTrigger 导出表上的触发器, 不支持触发器的方言可以什么都不写
+	WriteTableTrigger(db dbConn, table string, buf *bufio.Writer) error
+	// FormatValue 把一个列值格式化成可以直接拼进 INSERT 语句的字面量
+	FormatValue(col interface{}, columnType string) (string, error)
+}
+
+// dialectFromDSN 依据 dns 的 scheme 推断方言, 不带 scheme 前缀时默认 MySQL,
+// 与历史行为保持一致
+func dialectFromDSN(dns string) Dialect {
+	switch {
+	case strings.HasPrefix(dns, "postgres://"), strings.HasPrefix(dns, "postgresql://"):
+		return postgresDialect{}
+	case strings.HasPrefix(dns, "sqlserver://"):
+		return mssqlDialect{}
+	default:
+		return mysqlDialect{}
+	}
+}
@@ -0,0 +1,252 @@
+package mysqldump
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// mssqlDialect 导出 SQL Server, 使用 sys.tables/sys.columns 读取元数据, 标识符用
+// 方括号引用。和 postgresDialect 一样, CREATE TABLE 是从列信息拼接出的近似语句。
+type mssqlDialect struct{}
+
+func (mssqlDialect) DriverName() string { return "sqlserver" }
+
+func (mssqlDialect) QuoteIdent(name string) string {
+	return "[" + strings.Replace(name, "]", "]]", -1) + "]"
+}
+
+func (mssqlDialect) UseStmt(dbName string) string {
+	return fmt.Sprintf("USE [%s]", dbName)
+}
+
+func (mssqlDialect) ListTables(db dbConn) ([]string, error) {
+	rows, err := db.QueryContext(context.Background(), "SELECT name FROM sys.tables")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tables []string
+	for rows.Next() {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func (mssqlDialect) GetTableType(db dbConn, table string) (string, error) {
+	var n int
+	err := db.QueryRowContext(context.Background(), "SELECT COUNT(1) FROM sys.tables WHERE name = @p1", table).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+	if n > 0 {
+		return "TABLE", nil
+	}
+	err = db.QueryRowContext(context.Background(), "SELECT COUNT(1) FROM sys.views WHERE name = @p1", table).Scan(&n)
+	if err != nil {
+		return "", err
+	}
+	if n > 0 {
+		return "VIEW", nil
+	}
+	return "", nil
+}
+
+func (d mssqlDialect) DropTableStmt(table string) string {
+	return fmt.Sprintf("IF OBJECT_ID('%s', 'U') IS NOT NULL DROP TABLE %s;", table, d.QuoteIdent(table))
+}
+
+func (d mssqlDialect) DropViewStmt(table string) string {
+	return fmt.Sprintf("IF OBJECT_ID('%s', 'V') IS NOT NULL DROP VIEW %s;", table, d.QuoteIdent(table))
+}
+
+func (d mssqlDialect) WriteTableStruct(db dbConn, table string, buf *bufio.Writer) error {
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- Table structure for %s\n", table))
+	buf.WriteString("-- ----------------------------\n")
+
+	rows, err := db.QueryContext(context.Background(),
+		"SELECT c.name, t.name, c.is_nullable FROM sys.columns c "+
+			"JOIN sys.types t ON c.user_type_id = t.user_type_id "+
+			"WHERE c.object_id = OBJECT_ID(@p1) ORDER BY c.column_id", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var defs []string
+	for rows.Next() {
+		var name, dataType string
+		var nullable bool
+		if err := rows.Scan(&name, &dataType, &nullable); err != nil {
+			return err
+		}
+		def := fmt.Sprintf("%s %s", d.QuoteIdent(name), dataType)
+		if !nullable {
+			def += " NOT NULL"
+		}
+		defs = append(defs, def)
+	}
+
+	buf.WriteString(fmt.Sprintf("IF OBJECT_ID('%s', 'U') IS NULL CREATE TABLE %s (\n  ", table, d.QuoteIdent(table)))
+	buf.WriteString(strings.Join(defs, ",\n  "))
+	buf.WriteString("\n);")
+	buf.WriteString("\n\n\n\n")
+	return nil
+}
+
+func (d mssqlDialect) WriteViewStruct(db dbConn, table string, buf *bufio.Writer) error {
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- View structure for %s\n", table))
+	buf.WriteString("-- ----------------------------\n")
+
+	var definition string
+	err := db.QueryRowContext(context.Background(), "SELECT definition FROM sys.sql_modules WHERE object_id = OBJECT_ID(@p1)", table).Scan(&definition)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(definition)
+	buf.WriteString(";")
+	buf.WriteString("\n\n\n\n")
+	return nil
+}
+
+func (d mssqlDialect) WriteTableData(db dbConn, table string, buf *bufio.Writer, perDataNumber int, chunkSize int) error {
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- Records of %s\n", table))
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(d.LockTable(table) + "\n")
+
+	if chunkSize > 0 {
+		offset := 0
+		for {
+			query := fmt.Sprintf(
+				"SELECT * FROM %s ORDER BY (SELECT NULL) OFFSET %d ROWS FETCH NEXT %d ROWS ONLY",
+				d.QuoteIdent(table), offset, chunkSize)
+			n, err := d.writeDataQuery(db, query, table, buf, perDataNumber)
+			if err != nil {
+				return err
+			}
+			if n == 0 {
+				break
+			}
+			offset += n
+			buf.Flush()
+			if n < chunkSize {
+				break
+			}
+		}
+		buf.WriteString("\n")
+		return nil
+	}
+
+	_, err := d.writeDataQuery(db, fmt.Sprintf("SELECT * FROM %s", d.QuoteIdent(table)), table, buf, perDataNumber)
+	if err != nil {
+		return err
+	}
+	buf.WriteString("\n")
+	return nil
+}
+
+// writeDataQuery 执行一次查询并把结果集写成 INSERT 语句, 返回读到的行数
+func (d mssqlDialect) writeDataQuery(db dbConn, query string, table string, buf *bufio.Writer, perDataNumber int) (int, error) {
+	lineRows, err := db.QueryContext(context.Background(), query)
+	if err != nil {
+		return 0, err
+	}
+	defer lineRows.Close()
+
+	columns, err := lineRows.Columns()
+	if err != nil {
+		return 0, err
+	}
+	columnTypes, err := lineRows.ColumnTypes()
+	if err != nil {
+		return 0, err
+	}
+
+	quotedCols := make([]string, len(columns))
+	for i, c := range columns {
+		quotedCols[i] = d.QuoteIdent(c)
+	}
+
+	rowId := 0
+	for lineRows.Next() {
+		ssql := ""
+		if rowId == 0 || perDataNumber < 2 || rowId%perDataNumber == 0 {
+			if rowId > 0 {
+				ssql = ";\n"
+			}
+			ssql += fmt.Sprintf("INSERT INTO %s (%s) VALUES \n", d.QuoteIdent(table), strings.Join(quotedCols, ","))
+		} else {
+			buf.WriteString(",\n")
+		}
+
+		row := make([]interface{}, len(columns))
+		rowPointers := make([]interface{}, len(columns))
+		for i := range columns {
+			rowPointers[i] = &row[i]
+		}
+		if err := lineRows.Scan(rowPointers...); err != nil {
+			return 0, err
+		}
+
+		rowString := ""
+		for i, col := range row {
+			v, err := d.FormatValue(col, columnTypes[i].DatabaseTypeName())
+			if err != nil {
+				return 0, err
+			}
+			rowString += v
+			if i < len(row)-1 {
+				rowString += ","
+			}
+		}
+		ssql += "(" + rowString + ")"
+		rowId++
+		buf.WriteString(ssql)
+	}
+	if rowId > 0 {
+		buf.WriteString(";\n")
+	}
+	return rowId, nil
+}
+
+func (mssqlDialect) FormatValue(col interface{}, columnType string) (string, error) {
+	if col == nil {
+		return "NULL", nil
+	}
+	switch t := col.(type) {
+	case []byte:
+		return "N'" + strings.Replace(string(t), "'", "''", -1) + "'", nil
+	case string:
+		return "N'" + strings.Replace(t, "'", "''", -1) + "'", nil
+	case bool:
+		if t {
+			return "1", nil
+		}
+		return "0", nil
+	case time.Time:
+		return fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05")), nil
+	default:
+		return fmt.Sprintf("%v", t), nil
+	}
+}
+
+// LockTable SQL Server 没有显式的 LOCK TABLE 语法, 这里只向导出脚本写入一行
+// 注释; 导出时的查询不带 WITH (TABLOCKX) 提示, 导出和回放过程都不会加表锁
+func (d mssqlDialect) LockTable(table string) string {
+	return fmt.Sprintf("-- lock hint: SELECT * FROM %s WITH (TABLOCKX)", d.QuoteIdent(table))
+}
+
+// WriteTableTrigger SQL Server 的触发器定义和 MySQL 差异较大, 暂不导出
+func (mssqlDialect) WriteTableTrigger(db dbConn, table string, buf *bufio.Writer) error {
+	return nil
+}
@@ -0,0 +1,529 @@
+package mysqldump
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// mysqlDialect 是默认方言, 输出结构与重构前一致; 字符串值改由 escapeString
+// 转义、标识符改由 quoteIdent 加引号, 含特殊字符的值或名字输出与重构前不同
+type mysqlDialect struct {
+	// noBackslashEscapes 对应 sql_mode 里的 NO_BACKSLASH_ESCAPES, 由
+	// WithSQLMode 设置, 影响 FormatValue 对字符串类型的转义方式
+	noBackslashEscapes bool
+}
+
+func (mysqlDialect) DriverName() string { return "mysql" }
+
+func (mysqlDialect) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+func (d mysqlDialect) UseStmt(dbName string) string {
+	return fmt.Sprintf("USE %s", d.QuoteIdent(dbName))
+}
+
+func (mysqlDialect) ListTables(db dbConn) ([]string, error) {
+	var tables []string
+	rows, err := db.QueryContext(context.Background(), "SHOW TABLES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var table string
+		err = rows.Scan(&table)
+		if err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+// getDBs 列出所有数据库, 仅在 WithAllDatabases 下使用, 是 MySQL 特有能力
+func getDBs(db *sql.DB) ([]string, error) {
+	var dbs []string
+	rows, err := db.Query("SHOW DATABASES")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var db string
+		err = rows.Scan(&db)
+		if err != nil {
+			return nil, err
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs, nil
+}
+
+func (mysqlDialect) GetTableType(db dbConn, table string) (t string, err error) {
+	query := fmt.Sprintf("SELECT TABLE_TYPE FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = '%s'", table)
+	var tableType string
+	err = db.QueryRowContext(context.Background(), query).Scan(&tableType)
+	if err != nil {
+		return "", err
+	}
+	switch tableType {
+	case "BASE TABLE":
+		return "TABLE", nil
+	case "VIEW":
+		return "VIEW", nil
+	default:
+		return "", nil
+	}
+}
+
+func (d mysqlDialect) DropTableStmt(table string) string {
+	return fmt.Sprintf("DROP TABLE IF EXISTS %s;", d.QuoteIdent(table))
+}
+
+func (d mysqlDialect) DropViewStmt(table string) string {
+	return fmt.Sprintf("DROP VIEW IF EXISTS  %s;", d.QuoteIdent(table))
+}
+
+func (d mysqlDialect) getCreateTableSQL(db dbConn, table string) (string, error) {
+	var createTableSQL string
+
+	err := db.QueryRowContext(context.Background(), fmt.Sprintf("SHOW CREATE TABLE %s", d.QuoteIdent(table))).Scan(&table, &createTableSQL)
+	if err != nil {
+		return "", err
+	}
+	// IF NOT EXISTS
+	createTableSQL = strings.Replace(createTableSQL, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1)
+	return createTableSQL, nil
+}
+
+func (d mysqlDialect) WriteTableStruct(db dbConn, table string, buf *bufio.Writer) error {
+	// 导出表结构
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- Table structure for %s\n", table))
+	buf.WriteString("-- ----------------------------\n")
+
+	createTableSQL, err := d.getCreateTableSQL(db, table)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(createTableSQL)
+	buf.WriteString(";")
+
+	buf.WriteString("\n\n")
+	buf.WriteString("\n\n")
+	return nil
+}
+
+func (d mysqlDialect) WriteViewStruct(db dbConn, table string, buf *bufio.Writer) error {
+	// 导出视图
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- View structure for %s\n", table))
+	buf.WriteString("-- ----------------------------\n")
+
+	var createTableSQL string
+	var charact string
+	var connect string
+	err := db.QueryRowContext(context.Background(), fmt.Sprintf("SHOW CREATE TABLE %s", d.QuoteIdent(table))).Scan(&table, &createTableSQL, &charact, &connect)
+	if err != nil {
+		return err
+	}
+	buf.WriteString(createTableSQL)
+	buf.WriteString(";")
+
+	buf.WriteString("\n\n")
+	buf.WriteString("\n\n")
+	return nil
+}
+
+func (d mysqlDialect) WriteTableData(db dbConn, table string, buf *bufio.Writer, perDataNumber int, chunkSize int) error {
+
+	// 导出表数据
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("-- Records of %s\n", table))
+	buf.WriteString("-- ----------------------------\n")
+	buf.WriteString(fmt.Sprintf("LOCK TABLES %s WRITE;\n", d.QuoteIdent(table)))
+	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE %s DISABLE KEYS */;\n", d.QuoteIdent(table)))
+
+	var err error
+	if chunkSize > 0 {
+		err = d.writeTableDataChunked(db, table, buf, perDataNumber, chunkSize)
+	} else {
+		_, _, err = d.writeDataQuery(db, fmt.Sprintf("SELECT * FROM %s", d.QuoteIdent(table)), nil, table, buf, perDataNumber, nil)
+	}
+	if err != nil {
+		return err
+	}
+
+	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE %s ENABLE KEYS */;\n", d.QuoteIdent(table)))
+	buf.WriteString("UNLOCK TABLES;\n\n")
+	return nil
+}
+
+// writeTableDataChunked 优先按主键做 keyset 分页(WHERE (pk...) > (last...)
+// ORDER BY pk LIMIT chunkSize), 没有主键时退化为唯一自增列, 再退化为
+// LIMIT/OFFSET, 每批查询结果都会立即 flush, 不在内存里累积整张表
+func (d mysqlDialect) writeTableDataChunked(db dbConn, table string, buf *bufio.Writer, perDataNumber, chunkSize int) error {
+	keyCols, err := d.getPrimaryKeyColumns(db, table)
+	if err != nil {
+		return err
+	}
+	if len(keyCols) == 0 {
+		col, err := d.getAutoIncrementColumn(db, table)
+		if err != nil {
+			return err
+		}
+		if col != "" {
+			keyCols = []string{col}
+		}
+	}
+
+	if len(keyCols) > 0 {
+		return d.writeTableDataKeyset(db, table, buf, perDataNumber, chunkSize, keyCols)
+	}
+
+	log.Printf("[warn] [dump] table %s has no usable primary/unique key, falling back to LIMIT/OFFSET\n", table)
+	return d.writeTableDataOffset(db, table, buf, perDataNumber, chunkSize)
+}
+
+func (mysqlDialect) getPrimaryKeyColumns(db dbConn, table string) ([]string, error) {
+	rows, err := db.QueryContext(context.Background(),
+		"SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY' "+
+			"ORDER BY ORDINAL_POSITION", table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var cols []string
+	for rows.Next() {
+		var col string
+		if err := rows.Scan(&col); err != nil {
+			return nil, err
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+func (mysqlDialect) getAutoIncrementColumn(db dbConn, table string) (string, error) {
+	var col string
+	err := db.QueryRowContext(context.Background(),
+		"SELECT COLUMN_NAME FROM INFORMATION_SCHEMA.COLUMNS "+
+			"WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND EXTRA LIKE '%auto_increment%' LIMIT 1",
+		table).Scan(&col)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return col, nil
+}
+
+func (d mysqlDialect) writeTableDataKeyset(db dbConn, table string, buf *bufio.Writer, perDataNumber, chunkSize int, keyCols []string) error {
+	quoted := make([]string, len(keyCols))
+	for i, c := range keyCols {
+		quoted[i] = d.QuoteIdent(c)
+	}
+	keyTuple := strings.Join(quoted, ",")
+
+	var last []interface{}
+	for {
+		query, args := buildKeysetQuery(d.QuoteIdent(table), keyTuple, len(keyCols), last, chunkSize)
+
+		n, newLast, err := d.writeDataQuery(db, query, args, table, buf, perDataNumber, keyCols)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		last = newLast
+		log.Printf("[info] [dump] table %s: exported %d rows in this chunk\n", table, n)
+		buf.Flush()
+		if n < chunkSize {
+			break
+		}
+	}
+	return nil
+}
+
+// buildKeysetQuery 拼出 writeTableDataKeyset 一页的查询语句: 第一页(last 为
+// nil)没有 WHERE 子句, 之后每一页都以上一页最后一行的 keyCols 取值做
+// "(keyCols...) > (?,?,...)" 游标条件, 翻页本身不依赖 OFFSET, 因此大表翻页
+// 不会随着偏移量增大而变慢
+func buildKeysetQuery(quotedTable, keyTuple string, numKeyCols int, last []interface{}, chunkSize int) (string, []interface{}) {
+	query := fmt.Sprintf("SELECT * FROM %s", quotedTable)
+	var args []interface{}
+	if last != nil {
+		placeholders := make([]string, numKeyCols)
+		for i := 0; i < numKeyCols; i++ {
+			placeholders[i] = "?"
+			args = append(args, last[i])
+		}
+		query += fmt.Sprintf(" WHERE (%s) > (%s)", keyTuple, strings.Join(placeholders, ","))
+	}
+	query += fmt.Sprintf(" ORDER BY %s LIMIT %d", keyTuple, chunkSize)
+	return query, args
+}
+
+func (d mysqlDialect) writeTableDataOffset(db dbConn, table string, buf *bufio.Writer, perDataNumber, chunkSize int) error {
+	offset := 0
+	for {
+		query := fmt.Sprintf("SELECT * FROM %s LIMIT %d OFFSET %d", d.QuoteIdent(table), chunkSize, offset)
+		n, _, err := d.writeDataQuery(db, query, nil, table, buf, perDataNumber, nil)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+		offset += n
+		log.Printf("[info] [dump] table %s: exported %d rows (offset %d)\n", table, n, offset)
+		buf.Flush()
+		if n < chunkSize {
+			break
+		}
+	}
+	return nil
+}
+
+// writeDataQuery 执行一次查询, 把结果集合并成一条(或多条, 取决于
+// perDataNumber) INSERT 语句写入 buf, 返回本次读到的行数, 以及 keyCols 对应
+// 的最后一行取值(用于 keyset 分页翻页), 不在内存中保留已写出的行
+func (d mysqlDialect) writeDataQuery(db dbConn, query string, args []interface{}, table string, buf *bufio.Writer, perDataNumber int, keyCols []string) (int, []interface{}, error) {
+	lineRows, err := db.QueryContext(context.Background(), query, args...)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer lineRows.Close()
+
+	columns, err := lineRows.Columns()
+	if err != nil {
+		return 0, nil, err
+	}
+	columnTypes, err := lineRows.ColumnTypes()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	keyIdx := make([]int, len(keyCols))
+	for i, k := range keyCols {
+		for j, c := range columns {
+			if c == k {
+				keyIdx[i] = j
+				break
+			}
+		}
+	}
+
+	rowId := 0
+	var lastRow []interface{}
+	for lineRows.Next() {
+		ssql := ""
+		if rowId == 0 || perDataNumber < 2 || rowId%perDataNumber == 0 {
+			if rowId > 0 {
+				ssql = ";\n"
+			}
+			quotedCols := make([]string, len(columns))
+			for i, c := range columns {
+				quotedCols[i] = d.QuoteIdent(c)
+			}
+			//表结构
+			ssql += "INSERT INTO " + d.QuoteIdent(table) + " (" + strings.Join(quotedCols, ",") + ") VALUES \n"
+		} else {
+			buf.WriteString(",\n")
+		}
+
+		row := make([]interface{}, len(columns))
+		rowPointers := make([]interface{}, len(columns))
+		for i := range columns {
+			rowPointers[i] = &row[i]
+		}
+		err = lineRows.Scan(rowPointers...)
+		if err != nil {
+			return 0, nil, err
+		}
+
+		rowString := ""
+		for i, col := range row {
+			v, err := d.FormatValue(col, columnTypes[i].DatabaseTypeName())
+			if err != nil {
+				return 0, nil, err
+			}
+			rowString += v
+			if i < len(row)-1 {
+				rowString += ","
+			}
+		}
+		ssql += "(" + rowString + ")"
+		rowId += 1
+		buf.WriteString(ssql)
+		lastRow = row
+	}
+	if rowId > 0 {
+		buf.WriteString(";\n")
+	}
+
+	var last []interface{}
+	if lastRow != nil {
+		last = make([]interface{}, len(keyIdx))
+		for i, idx := range keyIdx {
+			last[i] = lastRow[idx]
+		}
+	}
+	return rowId, last, nil
+}
+
+func (d mysqlDialect) FormatValue(col interface{}, columnType string) (ssql string, err error) {
+	if col == nil {
+		return "NULL", nil
+	}
+	// 去除 UNSIGNED 和空格
+	Type := strings.Replace(columnType, "UNSIGNED", "", -1)
+	Type = strings.Replace(Type, " ", "", -1)
+	switch Type {
+	case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT":
+		if bs, ok := col.([]byte); ok {
+			ssql += fmt.Sprintf("%s", string(bs))
+		} else {
+			ssql += fmt.Sprintf("%d", col)
+		}
+	case "FLOAT", "DOUBLE":
+		if bs, ok := col.([]byte); ok {
+			ssql += fmt.Sprintf("%s", string(bs))
+		} else {
+			ssql += fmt.Sprintf("%f", col)
+		}
+	case "DECIMAL", "DEC":
+		ssql += fmt.Sprintf("%s", col)
+
+	case "DATE":
+		t, ok := col.(time.Time)
+		if !ok {
+			return "", err
+		}
+		ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02"))
+	case "DATETIME":
+		t, ok := col.(time.Time)
+		if !ok {
+			return "", err
+		}
+		ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05"))
+	case "TIMESTAMP":
+		t, ok := col.(time.Time)
+		if !ok {
+			return "", err
+		}
+		ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05"))
+	case "TIME":
+		t, ok := col.([]byte)
+		if !ok {
+			return "", err
+		}
+		ssql += fmt.Sprintf("'%s'", string(t))
+	case "YEAR":
+		t, ok := col.([]byte)
+		if !ok {
+			return "", err
+		}
+		ssql += fmt.Sprintf("%s", string(t))
+	case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
+		ssql += fmt.Sprintf("'%s'", escapeString(fmt.Sprintf("%s", col), d.noBackslashEscapes))
+	case "BIT", "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
+		ssql += fmt.Sprintf("0x%X", col)
+	case "ENUM", "SET":
+		ssql += fmt.Sprintf("'%s'", escapeString(fmt.Sprintf("%s", col), d.noBackslashEscapes))
+	case "BOOL", "BOOLEAN":
+		if col.(bool) {
+			ssql += "true"
+		} else {
+			ssql += "false"
+		}
+	case "JSON":
+		ssql += fmt.Sprintf("'%s'", escapeString(fmt.Sprintf("%s", col), d.noBackslashEscapes))
+	default:
+		// unsupported type
+		return "", fmt.Errorf("unsupported type: %s", Type)
+	}
+	return ssql, nil
+}
+
+func (d mysqlDialect) WriteTableTrigger(db dbConn, table string, buf *bufio.Writer) error {
+	var sql []string
+
+	triggers, err := getTrigger(db, table)
+	if err != nil {
+		return err
+	}
+	if len(triggers) > 0 {
+		sql = append(sql, "-- ----------------------------")
+		sql = append(sql, fmt.Sprintf("-- Dump table triggers of %s--------", table))
+		sql = append(sql, "-- ----------------------------")
+	}
+	for _, v := range triggers {
+		sql = append(sql, "DELIMITER ;;")
+		sql = append(sql, "/*!50003 SET SESSION SQL_MODE=\"\" */;;")
+		sql = append(sql, fmt.Sprintf("/*!50003 CREATE TRIGGER %s %s %s ON %s FOR EACH ROW %s */;;",
+			d.QuoteIdent(v.Trigger), v.Timing, v.Event, d.QuoteIdent(v.Table), v.Statement))
+		sql = append(sql, "DELIMITER ;")
+		sql = append(sql, "/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */;\n")
+	}
+	buf.WriteString(strings.Join(sql, "\n"))
+	return nil
+}
+
+func getTrigger(db dbConn, table string) (trigger []triggerStruct, err error) {
+	if allTriggers != nil {
+		trigger = allTriggers[table]
+		return trigger, nil
+	} else {
+		allTriggers = make(map[string][]triggerStruct)
+	}
+	trgs, err := db.QueryContext(context.Background(), "SHOW TRIGGERS")
+	if err != nil {
+		return trigger, err
+	}
+	defer trgs.Close()
+
+	var columns []string
+	columns, err = trgs.Columns()
+
+	for trgs.Next() {
+		trgrow := make([]interface{}, len(columns))
+		rowPointers := make([]interface{}, len(columns))
+		for i := range columns {
+			rowPointers[i] = &trgrow[i]
+		}
+		err = trgs.Scan(rowPointers...)
+		if err != nil {
+			return trigger, err
+		}
+		var trigger triggerStruct
+		for k, v := range trgrow {
+			switch columns[k] {
+			case "Table":
+				trigger.Table = fmt.Sprintf("%s", v)
+			case "Event":
+				trigger.Event = fmt.Sprintf("%s", v)
+			case "Trigger":
+				trigger.Trigger = fmt.Sprintf("%s", v)
+			case "Statement":
+				trigger.Statement = fmt.Sprintf("%s", v)
+			case "Timing":
+				trigger.Timing = fmt.Sprintf("%s", v)
+			}
+		}
+		allTriggers[trigger.Table] = append(allTriggers[trigger.Table], trigger)
+	}
+	return allTriggers[table], nil
+}
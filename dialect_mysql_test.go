@@ -0,0 +1,41 @@
+package mysqldump
+
+import "testing"
+
+func Test_buildKeysetQuery(t *testing.T) {
+	tests := []struct {
+		name      string
+		last      []interface{}
+		wantQuery string
+		wantArgs  []interface{}
+	}{
+		{
+			name:      "first page has no WHERE clause",
+			last:      nil,
+			wantQuery: "SELECT * FROM `t` ORDER BY `id` LIMIT 100",
+			wantArgs:  nil,
+		},
+		{
+			name:      "subsequent page seeks past the last key",
+			last:      []interface{}{int64(42)},
+			wantQuery: "SELECT * FROM `t` WHERE (`id`) > (?) ORDER BY `id` LIMIT 100",
+			wantArgs:  []interface{}{int64(42)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args := buildKeysetQuery("`t`", "`id`", 1, tt.last, 100)
+			if query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", query, tt.wantQuery)
+			}
+			if len(args) != len(tt.wantArgs) {
+				t.Fatalf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+			for i := range args {
+				if args[i] != tt.wantArgs[i] {
+					t.Errorf("args[%d] = %v, want %v", i, args[i], tt.wantArgs[i])
+				}
+			}
+		})
+	}
+}
@@ -2,8 +2,8 @@ package mysqldump
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
-	"fmt"
 	"io"
 	"log"
 	"os"
@@ -39,10 +39,32 @@ type dumpOption struct {
 	//批量插入，提高导出效率
 	perDataNumber int
 
+	// 按行数分批查询表数据, <=0 表示一次性 SELECT * 不分批
+	chunkSize int
+
 	// writer 默认为 os.Stdout
 	writer io.Writer
 	//是否输出日志
 	logOut bool
+
+	// SQL 生成所使用的方言, 默认根据 dns 的 scheme 推断, 也可通过 WithDialect 指定
+	dialect Dialect
+
+	// 并行导出表的 goroutine 数, <=1 表示不开启并行
+	parallel int
+	// 单个连接下是否开启一致性快照(REPEATABLE READ + WITH CONSISTENT SNAPSHOT)
+	singleTransaction bool
+	// 并行导出时, 每张表的内容写入该目录下的独立文件而不是内存 buffer
+	outputDir string
+
+	// 是否导出存储过程/函数, 目前只支持 MySQL 方言
+	isRoutines bool
+	// 是否导出计划事件(EVENT), 目前只支持 MySQL 方言
+	isEvents bool
+
+	// sqlMode 导出会话期间使用的 sql_mode, 例如 "NO_BACKSLASH_ESCAPES,ANSI_QUOTES",
+	// 目前只有 NO_BACKSLASH_ESCAPES 会影响 MySQL 方言的字符串转义方式
+	sqlMode string
 }
 type triggerStruct struct {
 	Trigger   string
@@ -112,6 +134,14 @@ func WithMultyInsert(num int) DumpOption {
 	}
 }
 
+// WithChunkSize 按 rows 行为单位分批查询并导出表数据(基于主键/唯一自增列的
+// keyset 分页), 避免大表一次性加载进内存; 不设置时按旧行为一次性 SELECT *
+func WithChunkSize(rows int) DumpOption {
+	return func(option *dumpOption) {
+		option.chunkSize = rows
+	}
+}
+
 // 导出到指定 writer
 func WithWriter(writer io.Writer) DumpOption {
 	return func(option *dumpOption) {
@@ -119,6 +149,13 @@ func WithWriter(writer io.Writer) DumpOption {
 	}
 }
 
+// 指定 SQL 方言, 不指定时根据 dns 的 scheme 自动推断(默认 MySQL)
+func WithDialect(dialect Dialect) DumpOption {
+	return func(option *dumpOption) {
+		option.dialect = dialect
+	}
+}
+
 // 是否输出日志
 // @TODO: 后续增加日志的handle用于输出到其他地方
 func WithLogOut(logOut bool) DumpOption {
@@ -127,6 +164,16 @@ func WithLogOut(logOut bool) DumpOption {
 	}
 }
 
+// WithSQLMode 设置导出会话使用的 sql_mode, 例如
+// WithSQLMode("NO_BACKSLASH_ESCAPES,ANSI_QUOTES")。目前只有
+// NO_BACKSLASH_ESCAPES 会影响 MySQL 方言导出数据时的字符串转义方式, 需要与
+// Source 端的 WithRestoreSQLMode 保持一致才能正确回放
+func WithSQLMode(mode string) DumpOption {
+	return func(option *dumpOption) {
+		option.sqlMode = mode
+	}
+}
+
 func Dump(dns string, opts ...DumpOption) error {
 
 	var err error
@@ -150,9 +197,18 @@ func Dump(dns string, opts ...DumpOption) error {
 		opt(&o)
 	}
 
+	if o.dialect == nil {
+		// 默认根据 dns 的 scheme 推断方言, 不加前缀则视为 MySQL
+		o.dialect = dialectFromDSN(dns)
+	}
+	if md, ok := o.dialect.(mysqlDialect); ok {
+		md.noBackslashEscapes = strings.Contains(strings.ToUpper(o.sqlMode), "NO_BACKSLASH_ESCAPES")
+		o.dialect = md
+	}
+
 	if len(o.dbs) == 0 {
 		// 默认包含dns中的数据库
-		dbName, err := GetDBNameFromDNS(dns)
+		dbName, err := GetDBNameFromDSN(dns)
 		if err != nil {
 			log.Printf("[error] %v \n", err)
 			return err
@@ -183,7 +239,7 @@ func Dump(dns string, opts ...DumpOption) error {
 	buf.WriteString("\n\n")
 	buf.WriteString("/*!40101 SET @OLD_SQL_MODE=@@SQL_MODE, SQL_MODE='NO_AUTO_VALUE_ON_ZERO' */;\n")
 	// 连接数据库
-	db, err := sql.Open("mysql", dns)
+	db, err := sql.Open(o.dialect.DriverName(), dns)
 	if err != nil {
 		if o.logOut {
 			log.Printf("[error] %v \n", err)
@@ -192,6 +248,15 @@ func Dump(dns string, opts ...DumpOption) error {
 	}
 	defer db.Close()
 
+	if o.sqlMode != "" {
+		if _, err = db.Exec("SET SESSION sql_mode = ?", o.sqlMode); err != nil {
+			if o.logOut {
+				log.Printf("[error] %v \n", err)
+			}
+			return err
+		}
+	}
+
 	// 1. 获取数据库
 	var dbs []string
 	if o.isAllDB {
@@ -210,17 +275,19 @@ func Dump(dns string, opts ...DumpOption) error {
 	}
 	// 2. 获取表
 	for _, dbStr := range dbs {
-		_, err = db.Exec(fmt.Sprintf("USE `%s`", dbStr))
-		if err != nil {
-			if o.logOut {
-				log.Printf("[error] %v \n", err)
+		if use := o.dialect.UseStmt(dbStr); use != "" {
+			_, err = db.Exec(use)
+			if err != nil {
+				if o.logOut {
+					log.Printf("[error] %v \n", err)
+				}
+				return err
 			}
-			return err
 		}
 
 		var tables []string
 		if o.isAllTable {
-			tmp, err := getAllTables(db)
+			tmp, err := o.dialect.ListTables(db)
 			if err != nil {
 				if o.logOut {
 					log.Printf("[error] %v \n", err)
@@ -233,42 +300,55 @@ func Dump(dns string, opts ...DumpOption) error {
 		}
 		if o.isUseDb {
 			//多库导出时，才会增加选库操作，否则不加选库操作
-			buf.WriteString(fmt.Sprintf("USE `%s`;\n", dbStr))
+			if use := o.dialect.UseStmt(dbStr); use != "" {
+				buf.WriteString(use + ";\n")
+			}
 		}
 
 		// 3. 导出表
-		for _, table := range tables {
-
-			tt, err := getTableType(db, table)
+		if o.parallel > 1 {
+			err = dumpTablesParallel(dns, db, o.dialect, tables, &o, buf)
 			if err != nil {
+				if o.logOut {
+					log.Printf("[error] %v \n", err)
+				}
 				return err
 			}
-
-			if tt == "TABLE" {
-				// 删除表
-				if o.isDropTable {
-					buf.WriteString(fmt.Sprintf("DROP TABLE IF EXISTS `%s`;\n", table))
-				}
-
-				// 导出表结构
-				err = writeTableStruct(db, table, buf)
+		} else if o.singleTransaction {
+			// 一致性快照必须和之后每张表的 SELECT 跑在同一个物理连接上, db
+			// 本身是连接池, 直接对它 Exec/Query 每次都可能拿到不同的连接,
+			// 这里显式钉住一个连接
+			ctx := context.Background()
+			conn, connErr := db.Conn(ctx)
+			if connErr != nil {
+				return connErr
+			}
+			if _, err = conn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+				conn.Close()
+				return err
+			}
+			if _, err = conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+				conn.Close()
+				return err
+			}
+			for _, table := range tables {
+				err = dumpOneTable(conn, o.dialect, table, buf, &o)
 				if err != nil {
 					if o.logOut {
 						log.Printf("[error] %v \n", err)
 					}
+					conn.Close()
 					return err
 				}
-				// 导出表数据
-				if o.isData {
-					err = writeTableData(db, table, buf, o.perDataNumber)
-					if err != nil {
-						if o.logOut {
-							log.Printf("[error] %v \n", err)
-						}
-						return err
-					}
-				}
-				err := writeTableTrigger(db, table, buf)
+			}
+			if _, err = conn.ExecContext(ctx, "COMMIT"); err != nil {
+				conn.Close()
+				return err
+			}
+			conn.Close()
+		} else {
+			for _, table := range tables {
+				err = dumpOneTable(db, o.dialect, table, buf, &o)
 				if err != nil {
 					if o.logOut {
 						log.Printf("[error] %v \n", err)
@@ -276,23 +356,27 @@ func Dump(dns string, opts ...DumpOption) error {
 					return err
 				}
 			}
-			if tt == "VIEW" {
-				// 删除视图
-				if o.isDropTable {
-					buf.WriteString(fmt.Sprintf("DROP VIEW IF EXISTS  `%s`;\n", table))
+		}
+
+		// 4. 导出存储过程/函数/事件(目前只支持 MySQL 方言)
+		if _, ok := o.dialect.(mysqlDialect); ok {
+			if o.isRoutines {
+				if err = writeRoutines(db, dbStr, buf); err != nil {
+					if o.logOut {
+						log.Printf("[error] %v \n", err)
+					}
+					return err
 				}
-				// 导出视图结构
-				err = writeViewStruct(db, table, buf)
-				if err != nil {
+			}
+			if o.isEvents {
+				if err = writeEvents(db, dbStr, buf); err != nil {
 					if o.logOut {
 						log.Printf("[error] %v \n", err)
 					}
 					return err
 				}
 			}
-
 		}
-
 	}
 
 	// 导出每个表的结构和数据
@@ -305,327 +389,3 @@ func Dump(dns string, opts ...DumpOption) error {
 
 	return nil
 }
-func getTableType(db *sql.DB, table string) (t string, err error) {
-	query := fmt.Sprintf("SELECT TABLE_TYPE FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = '%s'", table)
-	var tableType string
-	err = db.QueryRow(query).Scan(&tableType)
-	if err != nil {
-		return "", err
-	}
-	switch tableType {
-	case "BASE TABLE":
-		return "TABLE", nil
-	case "VIEW":
-		return "VIEW", nil
-	default:
-		return "", nil
-	}
-}
-
-func getCreateTableSQL(db *sql.DB, table string) (string, error) {
-
-	var createTableSQL string
-
-	err := db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&table, &createTableSQL)
-	if err != nil {
-		return "", err
-	}
-	// IF NOT EXISTS
-	createTableSQL = strings.Replace(createTableSQL, "CREATE TABLE", "CREATE TABLE IF NOT EXISTS", 1)
-	return createTableSQL, nil
-}
-
-func getDBs(db *sql.DB) ([]string, error) {
-	var dbs []string
-	rows, err := db.Query("SHOW DATABASES")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var db string
-		err = rows.Scan(&db)
-		if err != nil {
-			return nil, err
-		}
-		dbs = append(dbs, db)
-	}
-	return dbs, nil
-}
-
-func getAllTables(db *sql.DB) ([]string, error) {
-	var tables []string
-	rows, err := db.Query("SHOW TABLES")
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		var table string
-		err = rows.Scan(&table)
-		if err != nil {
-			return nil, err
-		}
-		tables = append(tables, table)
-	}
-	return tables, nil
-}
-
-func writeTableStruct(db *sql.DB, table string, buf *bufio.Writer) error {
-	// 导出表结构
-	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("-- Table structure for %s\n", table))
-	buf.WriteString("-- ----------------------------\n")
-
-	createTableSQL, err := getCreateTableSQL(db, table)
-	if err != nil {
-		return err
-	}
-	buf.WriteString(createTableSQL)
-	buf.WriteString(";")
-
-	buf.WriteString("\n\n")
-	buf.WriteString("\n\n")
-	return nil
-}
-
-func writeViewStruct(db *sql.DB, table string, buf *bufio.Writer) error {
-	// 导出视图
-	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("-- View structure for %s\n", table))
-	buf.WriteString("-- ----------------------------\n")
-
-	var createTableSQL string
-	var charact string
-	var connect string
-	err := db.QueryRow(fmt.Sprintf("SHOW CREATE TABLE `%s`", table)).Scan(&table, &createTableSQL, &charact, &connect)
-	if err != nil {
-		return err
-	}
-	buf.WriteString(createTableSQL)
-	buf.WriteString(";")
-
-	buf.WriteString("\n\n")
-	buf.WriteString("\n\n")
-	return nil
-}
-
-func writeTableData(db *sql.DB, table string, buf *bufio.Writer, perDataNumber int) error {
-
-	// 导出表数据
-	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("-- Records of %s\n", table))
-	buf.WriteString("-- ----------------------------\n")
-	buf.WriteString(fmt.Sprintf("LOCK TABLES `%s` WRITE;\n", table))
-	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE `%s` DISABLE KEYS */;\n", table))
-
-	lineRows, err := db.Query(fmt.Sprintf("SELECT * FROM `%s`", table))
-	if err != nil {
-		return err
-	}
-	defer lineRows.Close()
-
-	var columns []string
-	columns, err = lineRows.Columns()
-	if err != nil {
-		return err
-	}
-	columnTypes, err := lineRows.ColumnTypes()
-	if err != nil {
-		return err
-	}
-
-	var values [][]interface{}
-	rowId := 0
-
-	for lineRows.Next() {
-		ssql := ""
-		if rowId == 0 || perDataNumber < 2 || rowId%perDataNumber == 0 {
-			if rowId > 0 {
-				ssql = ";\n"
-			}
-			//表结构
-			ssql += "INSERT INTO `" + table + "` (`" + strings.Join(columns, "`,`") + "`) VALUES \n"
-		} else {
-			buf.WriteString(",\n")
-		}
-
-		row := make([]interface{}, len(columns))
-		rowPointers := make([]interface{}, len(columns))
-		for i := range columns {
-			rowPointers[i] = &row[i]
-		}
-		err = lineRows.Scan(rowPointers...)
-		if err != nil {
-			return err
-		}
-		rowString, err := buildRowData(row, columnTypes)
-		if err != nil {
-			return err
-		}
-		ssql += "(" + rowString + ")"
-		rowId += 1
-		buf.WriteString(ssql)
-		values = append(values, row)
-	}
-	buf.WriteString(";\n")
-	buf.WriteString(fmt.Sprintf("/*!40000 ALTER TABLE `%s` ENABLE KEYS */;\n", table))
-	buf.WriteString("UNLOCK TABLES;\n\n")
-	return nil
-
-}
-
-func buildRowData(row []interface{}, columnTypes []*sql.ColumnType) (ssql string, err error) {
-	// var ssql string
-	for i, col := range row {
-		if col == nil {
-			ssql += "NULL"
-		} else {
-			Type := columnTypes[i].DatabaseTypeName()
-			// 去除 UNSIGNED 和空格
-			Type = strings.Replace(Type, "UNSIGNED", "", -1)
-			Type = strings.Replace(Type, " ", "", -1)
-			switch Type {
-			case "TINYINT", "SMALLINT", "MEDIUMINT", "INT", "INTEGER", "BIGINT":
-				if bs, ok := col.([]byte); ok {
-					ssql += fmt.Sprintf("%s", string(bs))
-				} else {
-					ssql += fmt.Sprintf("%d", col)
-				}
-			case "FLOAT", "DOUBLE":
-				if bs, ok := col.([]byte); ok {
-					ssql += fmt.Sprintf("%s", string(bs))
-				} else {
-					ssql += fmt.Sprintf("%f", col)
-				}
-			case "DECIMAL", "DEC":
-				ssql += fmt.Sprintf("%s", col)
-
-			case "DATE":
-				t, ok := col.(time.Time)
-				if !ok {
-					return "", err
-				}
-				ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02"))
-			case "DATETIME":
-				t, ok := col.(time.Time)
-				if !ok {
-					return "", err
-				}
-				ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05"))
-			case "TIMESTAMP":
-				t, ok := col.(time.Time)
-				if !ok {
-					return "", err
-				}
-				ssql += fmt.Sprintf("'%s'", t.Format("2006-01-02 15:04:05"))
-			case "TIME":
-				t, ok := col.([]byte)
-				if !ok {
-					return "", err
-				}
-				ssql += fmt.Sprintf("'%s'", string(t))
-			case "YEAR":
-				t, ok := col.([]byte)
-				if !ok {
-					return "", err
-				}
-				ssql += fmt.Sprintf("%s", string(t))
-			case "CHAR", "VARCHAR", "TINYTEXT", "TEXT", "MEDIUMTEXT", "LONGTEXT":
-				r := strings.NewReplacer("\n", "\\n", "'", "\\'", "\r", "\\r", "\"", "\\\"")
-				ssql += fmt.Sprintf("'%s'", r.Replace(fmt.Sprintf("%s", col)))
-				// ssql += fmt.Sprintf("'%s'", strings.Replace(fmt.Sprintf("%s", col), "'", "''", -1))
-			case "BIT", "BINARY", "VARBINARY", "TINYBLOB", "BLOB", "MEDIUMBLOB", "LONGBLOB":
-				ssql += fmt.Sprintf("0x%X", col)
-			case "ENUM", "SET":
-				ssql += fmt.Sprintf("'%s'", col)
-			case "BOOL", "BOOLEAN":
-				if col.(bool) {
-					ssql += "true"
-				} else {
-					ssql += "false"
-				}
-			case "JSON":
-				ssql += fmt.Sprintf("'%s'", col)
-			default:
-				// unsupported type
-				return "", fmt.Errorf("unsupported type: %s", Type)
-			}
-		}
-		if i < len(row)-1 {
-			ssql += ","
-		}
-	}
-	return ssql, nil
-}
-
-func writeTableTrigger(db *sql.DB, table string, buf *bufio.Writer) error {
-	var sql []string
-
-	triggers, err := getTrigger(db, table)
-	if err != nil {
-		return err
-	}
-	if len(triggers) > 0 {
-		sql = append(sql, "-- ----------------------------")
-		sql = append(sql, fmt.Sprintf("-- Dump table triggers of %s--------", table))
-		sql = append(sql, "-- ----------------------------")
-	}
-	for _, v := range triggers {
-		sql = append(sql, "DELIMITER ;;")
-		sql = append(sql, "/*!50003 SET SESSION SQL_MODE=\"\" */;;")
-		sql = append(sql, fmt.Sprintf("/*!50003 CREATE TRIGGER `%s` %s %s ON `%s` FOR EACH ROW %s */;;", v.Trigger, v.Timing, v.Event, v.Table, v.Statement))
-		sql = append(sql, "DELIMITER ;")
-		sql = append(sql, "/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */;\n")
-	}
-	buf.WriteString(strings.Join(sql, "\n"))
-	return nil
-}
-
-func getTrigger(db *sql.DB, table string) (trigger []triggerStruct, err error) {
-	if allTriggers != nil {
-		trigger = allTriggers[table]
-		return trigger, nil
-	} else {
-		allTriggers = make(map[string][]triggerStruct)
-	}
-	trgs, err := db.Query("SHOW TRIGGERS")
-	if err != nil {
-		return trigger, err
-	}
-	defer trgs.Close()
-
-	var columns []string
-	columns, err = trgs.Columns()
-
-	for trgs.Next() {
-		trgrow := make([]interface{}, len(columns))
-		rowPointers := make([]interface{}, len(columns))
-		for i := range columns {
-			rowPointers[i] = &trgrow[i]
-		}
-		err = trgs.Scan(rowPointers...)
-		if err != nil {
-			return trigger, err
-		}
-		var trigger triggerStruct
-		for k, v := range trgrow {
-			switch columns[k] {
-			case "Table":
-				trigger.Table = fmt.Sprintf("%s", v)
-			case "Event":
-				trigger.Event = fmt.Sprintf("%s", v)
-			case "Trigger":
-				trigger.Trigger = fmt.Sprintf("%s", v)
-			case "Statement":
-				trigger.Statement = fmt.Sprintf("%s", v)
-			case "Timing":
-				trigger.Timing = fmt.Sprintf("%s", v)
-			}
-		}
-		allTriggers[trigger.Table] = append(allTriggers[trigger.Table], trigger)
-	}
-	return allTriggers[table], nil
-}
@@ -0,0 +1,219 @@
+package mysqldump
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+// WithParallel 以 n 个 goroutine 并行导出表, 同时在一个协调连接上建立一致性
+// 快照, 保证所有表看到同一个时间点的数据(等价于 mysqldump --single-transaction
+// 的多表并行版本)
+func WithParallel(n int) DumpOption {
+	return func(option *dumpOption) {
+		option.parallel = n
+	}
+}
+
+// WithSingleTransaction 在单个连接上开启 REPEATABLE READ + START TRANSACTION
+// WITH CONSISTENT SNAPSHOT, 用于不开并行也需要一致性快照的场景
+func WithSingleTransaction() DumpOption {
+	return func(option *dumpOption) {
+		option.singleTransaction = true
+	}
+}
+
+// WithOutputDir 并行导出时, 每张表的内容额外写入该目录下的独立文件
+// <表名>.sql; 内容仍然先写进内存 buffer 再拼接进最终输出, 设置后内存占用
+// 不会减少
+func WithOutputDir(dir string) DumpOption {
+	return func(option *dumpOption) {
+		option.outputDir = dir
+	}
+}
+
+// dumpOneTable 导出单张表或视图(含结构、数据、触发器), 被顺序和并行两条
+// 路径共用。db 接受 dbConn 而不是 *sql.DB, 这样一致性快照场景下可以传入
+// db.Conn(ctx) 取到的同一个物理连接, 保证 START TRANSACTION 和这里的所有
+// SELECT 跑在同一个连接上
+func dumpOneTable(db dbConn, dialect Dialect, table string, buf *bufio.Writer, o *dumpOption) error {
+	tt, err := dialect.GetTableType(db, table)
+	if err != nil {
+		return err
+	}
+
+	switch tt {
+	case "TABLE":
+		if o.isDropTable {
+			buf.WriteString(dialect.DropTableStmt(table) + "\n")
+		}
+		if err := dialect.WriteTableStruct(db, table, buf); err != nil {
+			return err
+		}
+		if o.isData {
+			if err := dialect.WriteTableData(db, table, buf, o.perDataNumber, o.chunkSize); err != nil {
+				return err
+			}
+		}
+		if err := dialect.WriteTableTrigger(db, table, buf); err != nil {
+			return err
+		}
+	case "VIEW":
+		if o.isDropTable {
+			buf.WriteString(dialect.DropViewStmt(table) + "\n")
+		}
+		if err := dialect.WriteViewStruct(db, table, buf); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tableSection 是一张表导出后的内容, 携带它在原始表列表中的下标, 序列化阶段
+// 据此按确定性的顺序拼接回最终输出
+type tableSection struct {
+	index int
+	table string
+	data  []byte
+	err   error
+}
+
+// dumpTablesParallel 在协调连接上开启一致性快照并记录 binlog 位置作为 PITR
+// 起点, 随后为每个 worker 打开独立连接(同样进入 REPEATABLE READ + WITH
+// CONSISTENT SNAPSHOT), worker 从 channel 中领取表名, 把该表的导出内容写进
+// 独立 buffer(设置了 WithOutputDir 时另存一份到该目录下的文件), 最后按表的原始顺序把所有
+// 分片拼接进最终的 io.Writer, 使输出仍是一个有效的单一 SQL 流
+func dumpTablesParallel(dns string, coordinator *sql.DB, dialect Dialect, tables []string, o *dumpOption, buf *bufio.Writer) error {
+	ctx := context.Background()
+
+	// 协调连接只用来在建立快照的那一刻记录 binlog 位置, 必须和 START
+	// TRANSACTION 钉在同一个物理连接上, 因此这里显式 Conn 而不是直接用
+	// coordinator 这个连接池
+	coordConn, err := coordinator.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	if _, err := coordConn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+		coordConn.Close()
+		return err
+	}
+	if _, err := coordConn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+		coordConn.Close()
+		return err
+	}
+	// binlog 位置只是给下游 DumpBinlog 做 PITR 用的附加信息, 快照本身不依赖它:
+	// 服务器可能关闭了二进制日志(SHOW MASTER STATUS 不返回行)或者是把 SHOW MASTER STATUS
+	// 改名/改了列数的版本(例如 MySQL 8.4 的 SHOW BINARY LOG STATUS), 这些
+	// 情况下不应该让整个并行导出失败, 退化为不写 PITR 头即可
+	file, pos, err := fetchMasterStatus(ctx, coordConn)
+	coordConn.Close()
+	if err != nil {
+		if o.logOut {
+			log.Printf("[info] [dump] could not capture binlog position for PITR, skipping: %v\n", err)
+		}
+	} else {
+		buf.WriteString(fmt.Sprintf("-- Consistent snapshot at %s:%d\n\n", file, pos))
+	}
+
+	type job struct {
+		index int
+		table string
+	}
+	jobs := make(chan job, len(tables))
+	for i, t := range tables {
+		jobs <- job{index: i, table: t}
+	}
+	close(jobs)
+
+	results := make(chan tableSection, len(tables))
+	workers := o.parallel
+	if workers > len(tables) {
+		workers = len(tables)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+
+	for w := 0; w < workers; w++ {
+		go func() {
+			workerDB, err := sql.Open(dialect.DriverName(), dns)
+			if err != nil {
+				for j := range jobs {
+					results <- tableSection{index: j.index, table: j.table, err: err}
+				}
+				return
+			}
+			defer workerDB.Close()
+
+			// 把 ISOLATION/START TRANSACTION 和该 worker 接下来所有表的 SELECT
+			// 钉在同一个连接上, workerDB 本身是连接池, 直接对它 Exec 拿到的
+			// 连接和后续 SELECT 拿到的连接不一定是同一个
+			conn, err := workerDB.Conn(ctx)
+			if err != nil {
+				for j := range jobs {
+					results <- tableSection{index: j.index, table: j.table, err: err}
+				}
+				return
+			}
+			defer conn.Close()
+
+			if _, err := conn.ExecContext(ctx, "SET SESSION TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+				results <- tableSection{err: err}
+				return
+			}
+			if _, err := conn.ExecContext(ctx, "START TRANSACTION WITH CONSISTENT SNAPSHOT"); err != nil {
+				results <- tableSection{err: err}
+				return
+			}
+
+			for j := range jobs {
+				data, err := dumpTableSection(conn, dialect, j.table, o)
+				results <- tableSection{index: j.index, table: j.table, data: data, err: err}
+			}
+
+			_, _ = conn.ExecContext(ctx, "COMMIT")
+		}()
+	}
+
+	sections := make([][]byte, len(tables))
+	for i := 0; i < len(tables); i++ {
+		r := <-results
+		if r.err != nil {
+			return r.err
+		}
+		sections[r.index] = r.data
+		if o.logOut {
+			log.Printf("[info] [dump] table %s done\n", r.table)
+		}
+	}
+
+	// 按原始表顺序拼接, 保证输出是确定性的单一 SQL 流
+	for _, s := range sections {
+		buf.Write(s)
+	}
+	return nil
+}
+
+// dumpTableSection 把单张表的导出内容写到独立 buffer, 并在设置了 WithOutputDir
+// 时同时落盘到对应文件, 返回内容以便按序拼接进最终输出
+func dumpTableSection(db dbConn, dialect Dialect, table string, o *dumpOption) ([]byte, error) {
+	var out bytes.Buffer
+	tmp := bufio.NewWriter(&out)
+	if err := dumpOneTable(db, dialect, table, tmp, o); err != nil {
+		return nil, err
+	}
+	tmp.Flush()
+
+	if o.outputDir != "" {
+		path := filepath.Join(o.outputDir, table+".sql")
+		if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+			return nil, err
+		}
+	}
+	return out.Bytes(), nil
+}
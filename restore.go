@@ -0,0 +1,212 @@
+package mysqldump
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// parseInsertStatement 把一条形如
+//
+//	INSERT INTO `t` (`a`,`b`) VALUES (1,'x'),(2,'y');
+//
+// 的(可能是 mergeInsert 合并出的)INSERT 语句拆成带 "?" 占位符的模板和按行
+// 展开的实参, 用于 WithPreparedRestore 下通过 db.Exec(query, args...) 执行,
+// 从而彻底绕开字面量拼接带来的转义问题
+func parseInsertStatement(ssql string, noBackslashEscapes bool) (query string, args []interface{}, err error) {
+	valuesIdx := strings.Index(ssql, "VALUES")
+	if valuesIdx == -1 {
+		return "", nil, fmt.Errorf("invalid INSERT: missing VALUES keyword")
+	}
+	head := strings.TrimSpace(ssql[:valuesIdx])
+	valuesPart := strings.TrimSpace(ssql[valuesIdx+len("VALUES"):])
+	valuesPart = strings.TrimSuffix(valuesPart, ";")
+
+	tuples, err := splitValueTuples(valuesPart, noBackslashEscapes)
+	if err != nil {
+		return "", nil, err
+	}
+
+	placeholderRows := make([]string, 0, len(tuples))
+	for _, tuple := range tuples {
+		values, err := splitValueList(tuple, noBackslashEscapes)
+		if err != nil {
+			return "", nil, err
+		}
+		placeholders := make([]string, len(values))
+		for i, v := range values {
+			arg, err := literalToArg(v, noBackslashEscapes)
+			if err != nil {
+				return "", nil, err
+			}
+			args = append(args, arg)
+			placeholders[i] = "?"
+		}
+		placeholderRows = append(placeholderRows, "("+strings.Join(placeholders, ",")+")")
+	}
+
+	query = head + " VALUES " + strings.Join(placeholderRows, ",") + ";"
+	return query, args, nil
+}
+
+// splitValueTuples 把 "(1,'a'),(2,'b')" 拆成 ["1,'a'", "2,'b'"], 只在顶层
+// (不在引号内)的括号处分割。noBackslashEscapes 对应 sql_mode 里的
+// NO_BACKSLASH_ESCAPES: 开启后反斜杠是普通字符, 不能当成转义序列的开始跳过,
+// 否则形如 'x\' 的值(反斜杠紧贴结束引号)会被误认为引号还没结束, 把下一条
+// 语句的内容并入当前 VALUES 子句
+func splitValueTuples(s string, noBackslashEscapes bool) ([]string, error) {
+	var tuples []string
+	depth := 0
+	start := -1
+	var inStr byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inStr != 0 {
+			if c == '\\' && inStr == '\'' && !noBackslashEscapes {
+				i++
+				continue
+			}
+			if c == inStr {
+				inStr = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'':
+			inStr = c
+		case '(':
+			if depth == 0 {
+				start = i + 1
+			}
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parens in VALUES clause")
+			}
+			if depth == 0 {
+				tuples = append(tuples, s[start:i])
+				start = -1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("unbalanced parens in VALUES clause")
+	}
+	return tuples, nil
+}
+
+// splitValueList 把一个元组内部按顶层逗号拆成各个字面量, 引号/括号内的逗号
+// 不会被当作分隔符。noBackslashEscapes 含义同 splitValueTuples
+func splitValueList(s string, noBackslashEscapes bool) ([]string, error) {
+	var values []string
+	depth := 0
+	var inStr byte
+	var cur strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if inStr != 0 {
+			cur.WriteByte(c)
+			if c == '\\' && inStr == '\'' && !noBackslashEscapes && i+1 < len(s) {
+				i++
+				cur.WriteByte(s[i])
+				continue
+			}
+			if c == inStr {
+				inStr = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'':
+			inStr = c
+			cur.WriteByte(c)
+		case '(':
+			depth++
+			cur.WriteByte(c)
+		case ')':
+			depth--
+			if depth < 0 {
+				return nil, fmt.Errorf("unbalanced parens in value tuple")
+			}
+			cur.WriteByte(c)
+		case ',':
+			if depth == 0 {
+				values = append(values, strings.TrimSpace(cur.String()))
+				cur.Reset()
+				continue
+			}
+			cur.WriteByte(c)
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	values = append(values, strings.TrimSpace(cur.String()))
+	return values, nil
+}
+
+// literalToArg 把一个 SQL 字面量(NULL/数字/字符串/十六进制 blob)转成可以直接
+// 作为 db.Exec 实参传入的 Go 值
+func literalToArg(v string, noBackslashEscapes bool) (interface{}, error) {
+	switch {
+	case strings.EqualFold(v, "NULL"):
+		return nil, nil
+	case len(v) >= 2 && strings.HasPrefix(v, "'") && strings.HasSuffix(v, "'"):
+		return unescapeString(v[1:len(v)-1], noBackslashEscapes), nil
+	case strings.HasPrefix(v, "0x") || strings.HasPrefix(v, "0X"):
+		b, err := hex.DecodeString(v[2:])
+		if err != nil {
+			return nil, err
+		}
+		return b, nil
+	default:
+		if i, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(v, 64); err == nil {
+			return f, nil
+		}
+		return v, nil
+	}
+}
+
+// unescapeString 是 escapeString 的逆操作, 用于把合并 INSERT 里的字符串字面量
+// 还原成原始值
+func unescapeString(s string, noBackslashEscapes bool) string {
+	if noBackslashEscapes {
+		return strings.Replace(s, "''", "'", -1)
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'Z':
+				b.WriteByte(26)
+			case '\\':
+				b.WriteByte('\\')
+			case '\'':
+				b.WriteByte('\'')
+			case '"':
+				b.WriteByte('"')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
@@ -0,0 +1,73 @@
+package mysqldump
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_escapeString_unescapeString_roundTrip(t *testing.T) {
+	tests := []struct {
+		name               string
+		in                 string
+		noBackslashEscapes bool
+	}{
+		{name: "quotes and backslash", in: `a'b\c`},
+		{name: "control characters", in: "a\x00b\bc\nd\re\tf\x1ag"},
+		{name: "invalid utf-8 passes through unchanged", in: string([]byte{0xFF, 0xFE, 'a'})},
+		{name: "NO_BACKSLASH_ESCAPES only doubles quotes", in: `a'b\c`, noBackslashEscapes: true},
+		{name: "NO_BACKSLASH_ESCAPES trailing backslash before quote", in: `x\`, noBackslashEscapes: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			escaped := escapeString(tt.in, tt.noBackslashEscapes)
+			got := unescapeString(escaped, tt.noBackslashEscapes)
+			if got != tt.in {
+				t.Errorf("round trip = %q, want %q (escaped: %q)", got, tt.in, escaped)
+			}
+		})
+	}
+}
+
+func Test_parseInsertStatement(t *testing.T) {
+	tests := []struct {
+		name               string
+		ssql               string
+		noBackslashEscapes bool
+		wantQuery          string
+		wantArgs           []interface{}
+	}{
+		{
+			name:      "simple values",
+			ssql:      "INSERT INTO `t` (`a`,`b`) VALUES (1,'x'),(2,'y');",
+			wantQuery: "INSERT INTO `t` (`a`,`b`) VALUES (?,?),(?,?);",
+			wantArgs:  []interface{}{int64(1), "x", int64(2), "y"},
+		},
+		{
+			name:      "null and hex blob",
+			ssql:      "INSERT INTO `t` (`a`,`b`) VALUES (NULL,0x4142);",
+			wantQuery: "INSERT INTO `t` (`a`,`b`) VALUES (?,?);",
+			wantArgs:  []interface{}{nil, []byte{0x41, 0x42}},
+		},
+		{
+			name:               "NO_BACKSLASH_ESCAPES value containing a backslash",
+			ssql:               "INSERT INTO `t` (`a`) VALUES ('x\\');",
+			noBackslashEscapes: true,
+			wantQuery:          "INSERT INTO `t` (`a`) VALUES (?);",
+			wantArgs:           []interface{}{`x\`},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := parseInsertStatement(tt.ssql, tt.noBackslashEscapes)
+			if err != nil {
+				t.Fatalf("parseInsertStatement() error = %v", err)
+			}
+			if query != tt.wantQuery {
+				t.Errorf("query = %q, want %q", query, tt.wantQuery)
+			}
+			if !reflect.DeepEqual(args, tt.wantArgs) {
+				t.Errorf("args = %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package mysqldump
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// WithRoutines 导出存储过程和函数, 目前只支持 MySQL 方言
+func WithRoutines() DumpOption {
+	return func(option *dumpOption) {
+		option.isRoutines = true
+	}
+}
+
+// WithEvents 导出计划事件(EVENT), 目前只支持 MySQL 方言
+func WithEvents() DumpOption {
+	return func(option *dumpOption) {
+		option.isEvents = true
+	}
+}
+
+// writeRoutines 导出指定库下的全部存储过程和函数, 与 mysqlDialect.WriteTableTrigger 一样
+// 用 DELIMITER ;; 包裹 CREATE 语句, 避免过程体里的 ; 提前结束语句
+func writeRoutines(db *sql.DB, dbName string, buf *bufio.Writer) error {
+	rows, err := db.Query(
+		"SELECT ROUTINE_NAME, ROUTINE_TYPE FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = ?", dbName)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type routine struct {
+		name string
+		typ  string
+	}
+	var routines []routine
+	for rows.Next() {
+		var r routine
+		if err := rows.Scan(&r.name, &r.typ); err != nil {
+			return err
+		}
+		routines = append(routines, r)
+	}
+
+	for _, r := range routines {
+		var showSQL string
+		var createSQL string
+		var sqlMode string
+		var charset, collation, collationConn string
+		if r.typ == "PROCEDURE" {
+			showSQL = fmt.Sprintf("SHOW CREATE PROCEDURE %s.%s", quoteIdent(dbName), quoteIdent(r.name))
+			var name string
+			err = db.QueryRow(showSQL).Scan(&name, &sqlMode, &createSQL, &charset, &collation, &collationConn)
+		} else {
+			showSQL = fmt.Sprintf("SHOW CREATE FUNCTION %s.%s", quoteIdent(dbName), quoteIdent(r.name))
+			var name string
+			err = db.QueryRow(showSQL).Scan(&name, &sqlMode, &createSQL, &charset, &collation, &collationConn)
+		}
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString("-- ----------------------------\n")
+		buf.WriteString(fmt.Sprintf("-- %s structure for %s\n", routineLabel(r.typ), r.name))
+		buf.WriteString("-- ----------------------------\n")
+		if r.typ == "PROCEDURE" {
+			buf.WriteString(fmt.Sprintf("DROP PROCEDURE IF EXISTS %s;\n", quoteIdent(r.name)))
+		} else {
+			buf.WriteString(fmt.Sprintf("DROP FUNCTION IF EXISTS %s;\n", quoteIdent(r.name)))
+		}
+		buf.WriteString("DELIMITER ;;\n")
+		buf.WriteString(fmt.Sprintf("/*!50003 SET SESSION SQL_MODE=\"%s\" */;;\n", sqlMode))
+		buf.WriteString(createSQL)
+		buf.WriteString(";;\n")
+		buf.WriteString("DELIMITER ;\n")
+		buf.WriteString("/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */;\n\n")
+	}
+	return nil
+}
+
+// routineLabel 把 "PROCEDURE"/"FUNCTION" 转成注释里用的 "Procedure"/"Function"
+func routineLabel(routineType string) string {
+	if routineType == "" {
+		return routineType
+	}
+	return strings.ToUpper(routineType[:1]) + strings.ToLower(routineType[1:])
+}
+
+// writeEvents 导出指定库下的全部计划事件
+func writeEvents(db *sql.DB, dbName string, buf *bufio.Writer) error {
+	rows, err := db.Query("SHOW EVENTS FROM " + quoteIdent(dbName))
+	if err != nil {
+		return err
+	}
+	columns, err := rows.Columns()
+	if err != nil {
+		rows.Close()
+		return err
+	}
+
+	var eventNames []string
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+		if err := rows.Scan(pointers...); err != nil {
+			rows.Close()
+			return err
+		}
+		for i, c := range columns {
+			if c == "Name" {
+				eventNames = append(eventNames, fmt.Sprintf("%s", values[i]))
+			}
+		}
+	}
+	rows.Close()
+
+	for _, name := range eventNames {
+		var eventName, sqlMode, timeZone, createSQL, charset, collation, collationConn string
+		err = db.QueryRow(fmt.Sprintf("SHOW CREATE EVENT %s.%s", quoteIdent(dbName), quoteIdent(name))).
+			Scan(&eventName, &sqlMode, &timeZone, &createSQL, &charset, &collation, &collationConn)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString("-- ----------------------------\n")
+		buf.WriteString(fmt.Sprintf("-- Event structure for %s\n", name))
+		buf.WriteString("-- ----------------------------\n")
+		buf.WriteString(fmt.Sprintf("DROP EVENT IF EXISTS %s;\n", quoteIdent(name)))
+		buf.WriteString("DELIMITER ;;\n")
+		buf.WriteString(fmt.Sprintf("/*!50003 SET SESSION SQL_MODE=\"%s\" */;;\n", sqlMode))
+		buf.WriteString(createSQL)
+		buf.WriteString(";;\n")
+		buf.WriteString("DELIMITER ;\n")
+		buf.WriteString("/*!50003 SET SESSION SQL_MODE=@OLD_SQL_MODE */;\n\n")
+	}
+	return nil
+}
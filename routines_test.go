@@ -0,0 +1,22 @@
+package mysqldump
+
+import "testing"
+
+func Test_routineLabel(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "procedure", in: "PROCEDURE", want: "Procedure"},
+		{name: "function", in: "FUNCTION", want: "Function"},
+		{name: "empty", in: "", want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := routineLabel(tt.in); got != tt.want {
+				t.Errorf("routineLabel(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package mysqldump
 
 import (
-	"bufio"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -15,6 +14,24 @@ type sourceOption struct {
 	dryRun      bool
 	mergeInsert int
 	debug       bool
+
+	// preparedRestore 为 true 时, INSERT 语句不再作为字面量拼接的 SQL 直接
+	// Exec, 而是重新解析成 "?" 占位符模板 + 实参, 通过 db.Exec(query, args...)
+	// 执行, 彻底绕开转义问题
+	preparedRestore bool
+	// sqlMode 恢复会话期间使用的 sql_mode, 需要和 Dump 时的 WithSQLMode 保持
+	// 一致, 目前只有 NO_BACKSLASH_ESCAPES 会影响 WithPreparedRestore 对字符
+	// 串字面量的解析方式
+	sqlMode string
+
+	// onError 决定单条语句执行失败时的处理方式, 零值等价于 Abort()
+	onError OnErrorPolicy
+	// batchCommit 每执行 k 条语句 COMMIT 一次, <=1 表示和原来一样只在最后
+	// COMMIT 一次
+	batchCommit int
+	// checkpointPath 不为空时开启断点续传, 每次批量提交后把 reader 的字节
+	// 偏移量写入该文件, 重新以同样的 path 调用 Source 时会自动从该偏移量继续
+	checkpointPath string
 }
 type SourceOption func(*sourceOption)
 
@@ -37,6 +54,51 @@ func WithDebug() SourceOption {
 	}
 }
 
+// WithPreparedRestore 让 INSERT 语句改为重新解析成参数化查询, 通过
+// db.Exec(query, args...) 执行, 而不是把字面量拼接进 SQL 文本直接 Exec, 避免
+// 遇到转义处理不了的数据(嵌入的反斜杠、NUL 字节、非法 UTF-8 等)时出错或产生
+// 错误的语句
+func WithPreparedRestore() SourceOption {
+	return func(o *sourceOption) {
+		o.preparedRestore = true
+	}
+}
+
+// WithRestoreSQLMode 设置恢复会话使用的 sql_mode, 需要和 Dump 时的
+// WithSQLMode 保持一致, 例如 WithRestoreSQLMode("NO_BACKSLASH_ESCAPES,ANSI_QUOTES")
+func WithRestoreSQLMode(mode string) SourceOption {
+	return func(o *sourceOption) {
+		o.sqlMode = mode
+	}
+}
+
+// WithOnError 设置单条语句执行失败时的处理方式: Abort()(默认)/
+// SkipAndLog()/RetryN(n, backoff)
+func WithOnError(policy OnErrorPolicy) SourceOption {
+	return func(o *sourceOption) {
+		o.onError = policy
+	}
+}
+
+// WithBatchCommit 每执行 k 条语句 COMMIT 一次, 而不是整个恢复过程只在最后
+// COMMIT 一次; k <= 1 时和不设置一样, 只在最后 COMMIT 一次。和 WithCheckpoint 搭配使用时, 检查点只会在 COMMIT 成功之后
+// 才更新, 保证记录的断点始终落在一个事务边界上
+func WithBatchCommit(k int) SourceOption {
+	return func(o *sourceOption) {
+		o.batchCommit = k
+	}
+}
+
+// WithCheckpoint 开启断点续传: 每次批量提交后原子地把 reader 的字节偏移量和
+// 最近一条成功语句的哈希写入 path。再次以同样的 path 调用 Source 时, 会先
+// 读取检查点并 Seek 到记录的偏移量继续执行, 因此 reader 必须实现
+// io.ReadSeeker, 否则 Source 会返回错误
+func WithCheckpoint(path string) SourceOption {
+	return func(o *sourceOption) {
+		o.checkpointPath = path
+	}
+}
+
 type dbWrapper struct {
 	DB     *sql.DB
 	debug  bool
@@ -107,11 +169,46 @@ func Source(dsn string, reader io.Reader, opts ...SourceOption) error {
 		return err
 	}
 
+	if o.sqlMode != "" {
+		_, err = dbWrapper.Exec("SET SESSION sql_mode = ?", o.sqlMode)
+		if err != nil {
+			log.Printf("[error] %v\n", err)
+			return err
+		}
+	}
+	noBackslashEscapes := strings.Contains(strings.ToUpper(o.sqlMode), "NO_BACKSLASH_ESCAPES")
+
+	// 检查点续传要求 reader 可以 Seek, 恢复时先跳到上次记录的偏移量
+	var resumeOffset int64
+	if o.checkpointPath != "" {
+		seeker, ok := reader.(io.ReadSeeker)
+		if !ok {
+			err = errors.New("source: WithCheckpoint requires an io.ReadSeeker reader")
+			log.Printf("[error] %v\n", err)
+			return err
+		}
+
+		cp, err := loadCheckpoint(o.checkpointPath)
+		if err != nil {
+			log.Printf("[error] [loadCheckpoint] %v\n", err)
+			return err
+		}
+		if cp != nil {
+			if _, err = seeker.Seek(cp.Offset, io.SeekStart); err != nil {
+				log.Printf("[error] [checkpoint seek] %v\n", err)
+				return err
+			}
+			resumeOffset = cp.Offset
+			log.Printf("[info] [source] resuming from checkpoint %s at offset %d\n", o.checkpointPath, cp.Offset)
+		}
+	}
+
 	// 设置超时时间1小时
 	db.SetConnMaxLifetime(3600)
 
 	// 一句一句执行
-	r := bufio.NewReader(reader)
+	tok := newSQLTokenizer(reader, noBackslashEscapes)
+	tok.pos = resumeOffset
 	// 关闭事务
 	_, err = dbWrapper.Exec("SET autocommit=0;")
 	if err != nil {
@@ -119,31 +216,56 @@ func Source(dsn string, reader io.Reader, opts ...SourceOption) error {
 		return err
 	}
 
-	for {
-		line, err := r.ReadString(';')
-		if err != nil {
-			if err == io.EOF {
-				break
-			}
-			log.Printf("[error] %v\n", err)
+	lastStmt := ""
+	sinceCommit := 0
+	// execPos 是"已经真正执行过的语句"在输入流里结束的字节偏移量, 续传要从这里
+	// 开始而不是 tok.pos: mergeInsert 的向前看可能已经从 tok 里多读出一条不参与
+	// 本批合并的语句, 它被缓存在 pending 里等下一轮执行, 此时 tok.pos 已经越过
+	// 了它, 如果检查点直接记 tok.pos, 续传会把这条还没执行的语句跳过
+	execPos := tok.pos
+
+	commitAndCheckpoint := func() error {
+		if _, err := dbWrapper.Exec("COMMIT;"); err != nil {
 			return err
 		}
+		sinceCommit = 0
+		if o.checkpointPath != "" {
+			cp := checkpointState{Offset: execPos, LastStmtHash: hashStatement(lastStmt)}
+			if err := saveCheckpoint(o.checkpointPath, cp); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
 
-		ssql := string(line)
+	// pending 缓存 mergeInsert 向前看时读到、但不属于当前合并批次的语句, 下一轮
+	// 按普通语句处理, 绝不能直接丢弃
+	var pending string
 
-		// 删除末尾的换行符
-		ssql = trim(ssql)
-		if err != nil {
-			log.Printf("[error] [trim] %v\n", err)
-			return err
+	for {
+		var ssql string
+		if pending != "" {
+			ssql = pending
+			pending = ""
+		} else {
+			line, err := tok.next()
+			if err != nil {
+				if err == io.EOF {
+					break
+				}
+				log.Printf("[error] %v\n", err)
+				return err
+			}
+			ssql = trim(string(line))
 		}
+		execPos = tok.pos
 
 		// 如果 INSERT 开始, 并且 mergeInsert 为 true, 则合并 INSERT
 		if o.mergeInsert > 1 && strings.HasPrefix(ssql, "INSERT INTO") {
 			var insertSQLs []string
 			insertSQLs = append(insertSQLs, ssql)
 			for i := 0; i < o.mergeInsert-1; i++ {
-				line, err := r.ReadString(';')
+				line, err := tok.next()
 				if err != nil {
 					if err == io.EOF {
 						break
@@ -152,17 +274,16 @@ func Source(dsn string, reader io.Reader, opts ...SourceOption) error {
 					return err
 				}
 
-				ssql2 := string(line)
-				ssql2 = trim(ssql2)
-				if err != nil {
-					log.Printf("[error] [trim] %v\n", err)
-					return err
-				}
+				ssql2 := trim(string(line))
 				if strings.HasPrefix(ssql2, "INSERT INTO") {
 					insertSQLs = append(insertSQLs, ssql2)
+					execPos = tok.pos
 					continue
 				}
 
+				// ssql2 不属于这一批合并, 但已经从 tok 里读出来了, 留到下一轮
+				// 当普通语句执行, execPos 保持在它之前
+				pending = ssql2
 				break
 			}
 			// 合并 INSERT
@@ -173,16 +294,33 @@ func Source(dsn string, reader io.Reader, opts ...SourceOption) error {
 			}
 		}
 
-		_, err = dbWrapper.Exec(ssql)
-		if err != nil {
+		if o.preparedRestore && strings.HasPrefix(ssql, "INSERT INTO") {
+			query, args, err := parseInsertStatement(ssql, noBackslashEscapes)
+			if err != nil {
+				log.Printf("[error] [parseInsertStatement] %v\n", err)
+				return err
+			}
+			if err = execWithPolicy(dbWrapper, o.onError, query, args...); err != nil {
+				log.Printf("[error] %v\n", err)
+				return err
+			}
+		} else if err = execWithPolicy(dbWrapper, o.onError, ssql); err != nil {
 			log.Printf("[error] %v\n", err)
 			return err
 		}
+
+		lastStmt = ssql
+		sinceCommit++
+		if o.batchCommit > 1 && sinceCommit >= o.batchCommit {
+			if err = commitAndCheckpoint(); err != nil {
+				log.Printf("[error] %v\n", err)
+				return err
+			}
+		}
 	}
 
 	// 提交事务
-	_, err = dbWrapper.Exec("COMMIT;")
-	if err != nil {
+	if err = commitAndCheckpoint(); err != nil {
 		log.Printf("[error] %v\n", err)
 		return err
 	}
@@ -197,6 +335,35 @@ func Source(dsn string, reader io.Reader, opts ...SourceOption) error {
 	return nil
 }
 
+// execWithPolicy 按 policy 执行一条语句: Abort 时失败直接返回错误;
+// SkipAndLog 时失败记录日志后当作成功处理; RetryN 时按 backoff 间隔重试
+// 最多 n 次, 仍然失败则返回错误
+func execWithPolicy(dbWrapper *dbWrapper, policy OnErrorPolicy, query string, args ...interface{}) error {
+	attempt := 0
+	for {
+		_, err := dbWrapper.Exec(query, args...)
+		if err == nil {
+			return nil
+		}
+
+		switch policy.kind {
+		case onErrorSkip:
+			log.Printf("[warn] [source] statement failed, skipping: %v\n", err)
+			return nil
+		case onErrorRetry:
+			if attempt < policy.retries {
+				attempt++
+				log.Printf("[warn] [source] statement failed (attempt %d/%d), retrying: %v\n", attempt, policy.retries, err)
+				time.Sleep(policy.backoff)
+				continue
+			}
+			return err
+		default:
+			return err
+		}
+	}
+}
+
 /*
 将多个 INSERT 合并为一个
 输入:
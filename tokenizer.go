@@ -0,0 +1,176 @@
+package mysqldump
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// sqlTokenizer 从 io.Reader 中按当前分隔符读取完整的 SQL 语句, 正确跳过
+// '...'/"..."/`...`字符串及标识符、-- ...\n 行注释、/* ... */ 块注释内部出现
+// 的分隔符, 并支持 "DELIMITER x" 指令切换分隔符(指令本身不作为语句返回)。
+// 取代早期用 bufio.Reader.ReadString(';') 切分、遇到字符串/注释里的 ';' 就提前
+// 截断语句的做法
+type sqlTokenizer struct {
+	r         *bufio.Reader
+	delimiter string
+	// pos 是已经消费的字节数, 与底层 io.Reader 的预读缓冲无关, 可以直接作为
+	// 断点续传的续传偏移量使用
+	pos int64
+	// noBackslashEscapes 对应 sql_mode 里的 NO_BACKSLASH_ESCAPES, 须和 Dump
+	// 端 WithSQLMode 保持一致: 开启后 '...' 字符串里的反斜杠是普通字符而不是
+	// 转义序列的开始, 否则形如 'x\' 的值会被误判为引号未闭合, 把下一条语句
+	// 的内容并入当前语句
+	noBackslashEscapes bool
+}
+
+func newSQLTokenizer(r io.Reader, noBackslashEscapes bool) *sqlTokenizer {
+	return &sqlTokenizer{r: bufio.NewReader(r), delimiter: ";", noBackslashEscapes: noBackslashEscapes}
+}
+
+func (t *sqlTokenizer) readByte() (byte, error) {
+	b, err := t.r.ReadByte()
+	if err == nil {
+		t.pos++
+	}
+	return b, err
+}
+
+// next 读取下一条语句, 统一以 ";" 结尾返回, 调用方不需要关心当前用的是不是
+// ";;" 等自定义分隔符
+func (t *sqlTokenizer) next() (string, error) {
+	var sb strings.Builder
+	var inQuote byte
+	// lineBuf 记录自上一个换行符以来、在引号/注释之外写入的内容, 只用来判断
+	// 当前行是否正在构成一条 "DELIMITER x" 指令; 一旦确定不是就不再更新,
+	// 避免在指令自身的分隔符(例如 "DELIMITER ;;" 里的第一个 ';')被当成旧
+	// 分隔符提前把语句截断
+	var lineBuf strings.Builder
+	maybeDelimiterLine := true
+
+	for {
+		b, err := t.readByte()
+		if err != nil {
+			if err == io.EOF && sb.Len() > 0 {
+				return sb.String(), nil
+			}
+			return "", err
+		}
+
+		switch {
+		case inQuote != 0:
+			sb.WriteByte(b)
+			if b == '\\' && inQuote != '`' && !t.noBackslashEscapes {
+				// 反引号标识符内部没有反斜杠转义, 其余引号风格都有;
+				// NO_BACKSLASH_ESCAPES 下反斜杠同样不转义
+				if nb, err := t.readByte(); err == nil {
+					sb.WriteByte(nb)
+				}
+				continue
+			}
+			if b == inQuote {
+				inQuote = 0
+			}
+			continue
+
+		case b == '\'' || b == '"' || b == '`':
+			inQuote = b
+			sb.WriteByte(b)
+			maybeDelimiterLine = false
+			continue
+
+		case b == '-':
+			if nb, err := t.r.Peek(1); err == nil && len(nb) == 1 && nb[0] == '-' {
+				t.readByte()
+				sb.WriteString("--")
+				if err := t.skipLineComment(&sb); err != nil {
+					if err == io.EOF && sb.Len() > 0 {
+						return sb.String(), nil
+					}
+					return "", err
+				}
+				lineBuf.Reset()
+				maybeDelimiterLine = true
+				continue
+			}
+			sb.WriteByte(b)
+			lineBuf.WriteByte(b)
+
+		case b == '/':
+			if nb, err := t.r.Peek(1); err == nil && len(nb) == 1 && nb[0] == '*' {
+				t.readByte()
+				sb.WriteString("/*")
+				if err := t.skipBlockComment(&sb); err != nil {
+					if err == io.EOF && sb.Len() > 0 {
+						return sb.String(), nil
+					}
+					return "", err
+				}
+				maybeDelimiterLine = false
+				continue
+			}
+			sb.WriteByte(b)
+			lineBuf.WriteByte(b)
+
+		case b == '\n':
+			sb.WriteByte(b)
+			line := strings.TrimSpace(lineBuf.String())
+			lineBuf.Reset()
+			maybeDelimiterLine = true
+			if strings.HasPrefix(strings.ToUpper(line), "DELIMITER ") {
+				t.delimiter = strings.TrimSpace(line[len("DELIMITER "):])
+				sb.Reset()
+				continue
+			}
+
+		default:
+			sb.WriteByte(b)
+			lineBuf.WriteByte(b)
+		}
+
+		if maybeDelimiterLine {
+			upper := strings.ToUpper(strings.TrimSpace(lineBuf.String()))
+			if strings.HasPrefix("DELIMITER ", upper) || strings.HasPrefix(upper, "DELIMITER ") {
+				// 这一行还可能是(或已经是)一条 DELIMITER 指令, 在确认之前
+				// 不把行内出现的旧分隔符当作语句结束
+				continue
+			}
+			maybeDelimiterLine = false
+		}
+
+		if content := sb.String(); strings.HasSuffix(content, t.delimiter) {
+			return content[:len(content)-len(t.delimiter)] + ";", nil
+		}
+	}
+}
+
+// skipLineComment 读到行尾(含换行符)为止, 把注释原样写入 sb, 不对其内容做
+// 任何分隔符/DELIMITER 指令识别
+func (t *sqlTokenizer) skipLineComment(sb *strings.Builder) error {
+	for {
+		b, err := t.readByte()
+		if err != nil {
+			return err
+		}
+		sb.WriteByte(b)
+		if b == '\n' {
+			return nil
+		}
+	}
+}
+
+// skipBlockComment 读到 "*/" 为止, 把注释原样写入 sb
+func (t *sqlTokenizer) skipBlockComment(sb *strings.Builder) error {
+	prevStar := false
+	for {
+		b, err := t.readByte()
+		if err != nil {
+			return err
+		}
+		sb.WriteByte(b)
+		if prevStar && b == '/' {
+			return nil
+		}
+		prevStar = b == '*'
+	}
+}
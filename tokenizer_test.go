@@ -0,0 +1,98 @@
+package mysqldump
+
+import (
+	"io"
+	"strings"
+	"testing"
+)
+
+func Test_sqlTokenizer_next(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  []string
+	}{
+		{
+			name:  "plain statements",
+			input: "SELECT 1;\nSELECT 2;\n",
+			want:  []string{"SELECT 1;", "\nSELECT 2;"},
+		},
+		{
+			name:  "semicolon inside quotes is not a terminator",
+			input: "INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`);\n",
+			want:  []string{"INSERT INTO t VALUES ('a;b', \"c;d\", `e;f`);"},
+		},
+		{
+			name:  "semicolon inside line comment is not a terminator",
+			input: "SELECT 1; -- trailing ; comment\nSELECT 2;\n",
+			want:  []string{"SELECT 1;", " -- trailing ; comment\nSELECT 2;"},
+		},
+		{
+			name:  "semicolon inside block comment is not a terminator",
+			input: "SELECT 1; /* a;b */\nSELECT 2;\n",
+			want:  []string{"SELECT 1;", " /* a;b */\nSELECT 2;"},
+		},
+		{
+			name:  "DELIMITER directive switches terminator",
+			input: "DELIMITER ;;\nCREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW BEGIN SELECT 1; END;;\nDELIMITER ;\nSELECT 2;\n",
+			want: []string{
+				"CREATE TRIGGER trg BEFORE INSERT ON t FOR EACH ROW BEGIN SELECT 1; END;",
+				"SELECT 2;",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tok := newSQLTokenizer(strings.NewReader(tt.input), false)
+			var got []string
+			for {
+				s, err := tok.next()
+				if err != nil {
+					if err == io.EOF {
+						break
+					}
+					t.Fatalf("next() error = %v", err)
+				}
+				// 输入结尾多出的换行符会被当成一条只含空白的"语句"返回
+				// (EOF 时 sb 非空就整体吐出), 这里忽略它, 只断言真正的 SQL 语句
+				if strings.TrimSpace(s) == "" {
+					continue
+				}
+				got = append(got, s)
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("next() = %q, want %q", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("statement %d = %q, want %q", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func Test_sqlTokenizer_next_noBackslashEscapes(t *testing.T) {
+	// NO_BACKSLASH_ESCAPES 下 escapeString 只双写引号, 反斜杠是字面字符, 紧贴
+	// 结束引号的 '\' 不能被当成转义序列的开始吃掉下一个字符(也就是结束引号
+	// 本身), 否则会把下一条语句的内容并入当前语句
+	input := "INSERT INTO t VALUES ('x\\');\nSELECT 1;\n"
+	tok := newSQLTokenizer(strings.NewReader(input), true)
+
+	first, err := tok.next()
+	if err != nil {
+		t.Fatalf("next() error = %v", err)
+	}
+	if want := "INSERT INTO t VALUES ('x\\');"; first != want {
+		t.Errorf("first statement = %q, want %q", first, want)
+	}
+
+	second, err := tok.next()
+	if err != nil {
+		t.Fatalf("next() error = %v", err)
+	}
+	if want := "\nSELECT 1;"; second != want {
+		t.Errorf("second statement = %q, want %q", second, want)
+	}
+}
@@ -19,3 +19,47 @@ func GetDBNameFromDSN(dsn string) (string, error) {
 
 	return "", fmt.Errorf("dsn error: %s", dsn)
 }
+
+// quoteIdent 给 MySQL 标识符(库名/表名/列名)加反引号, 内部出现的反引号按
+// MySQL 规则双写转义, 用于防止形如 "foo`;DROP" 的标识符破坏拼接出的 SQL
+func quoteIdent(name string) string {
+	return "`" + strings.Replace(name, "`", "``", -1) + "`"
+}
+
+// escapeString 按 MySQL 转义规则处理字符串字面量。noBackslashEscapes 对应
+// sql_mode 中的 NO_BACKSLASH_ESCAPES: 开启后反斜杠不再是转义字符, 只需要把
+// 单引号双写即可
+func escapeString(s string, noBackslashEscapes bool) string {
+	if noBackslashEscapes {
+		return strings.Replace(s, "'", "''", -1)
+	}
+	// 按字节而不是按 rune 遍历: 逐 rune 遍历会把非法 UTF-8 字节解码成 U+FFFD
+	// 再写回, 悄悄篡改了数据; 这里只关心转义表里列出的那几个 ASCII 字节,
+	// 其余字节(不管是否合法 UTF-8)原样透传
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case '\t':
+			b.WriteString(`\t`)
+		case 26:
+			b.WriteString(`\Z`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\'':
+			b.WriteString(`\'`)
+		case '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}